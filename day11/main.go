@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"github.com/mbordner/advent_of_code_2019/intcode"
 	"strings"
 )
 
@@ -149,7 +150,9 @@ func main() {
 	out := make(chan string, 1)
 	quit := make(chan string, 1)
 
-	c := NewIntCodeComputer(getProgram(), in, out, quit, true)
+	prompt := "input:"
+
+	c := intcode.NewIntCodeComputer(getProgram(), in, out, quit, true, &prompt)
 	r := NewRobot()
 
 	r.Paint(White)
@@ -169,7 +172,7 @@ func main() {
 programLoop:
 	for {
 		select {
-		case <-in:
+		case <-c.GetPromptChannel():
 
 			color := getPanelColor(r.GetPosition())
 			//fmt.Printf("current color: %s\n",color)
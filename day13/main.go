@@ -2,7 +2,7 @@ package main
 
 import (
 	"github.com/mbordner/advent_of_code_2019/day13/game"
-	"github.com/mbordner/advent_of_code_2019/day13/intcode"
+	"github.com/mbordner/advent_of_code_2019/intcode"
 	"errors"
 	"fmt"
 	"strconv"
@@ -18,7 +18,7 @@ func main() {
 	program := getProgram()
 	program[0] = "2" // need to set this to 2 so that we can play for free, memory address 0 represents the number of quarters inserted
 
-	c := intcode.NewIntCodeComputer(program, in, out, compquit, true)
+	c := intcode.NewIntCodeComputer(program, in, out, compquit, true, nil)
 	g := game.NewGame(in, compquit, quit)
 
 	go c.Execute()
@@ -82,6 +82,10 @@ programLoop:
 		}
 	}
 
+	if err := c.Err(); err != nil {
+		fmt.Println("program fault: ", err)
+	}
+
 	/*
 		blocksCount := 0
 		objects := g.GetObjects()
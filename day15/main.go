@@ -4,7 +4,7 @@ import (
 	"github.com/mbordner/advent_of_code_2019/day15/game"
 	"github.com/mbordner/advent_of_code_2019/day15/geom"
 	"github.com/mbordner/advent_of_code_2019/day15/graph"
-	"github.com/mbordner/advent_of_code_2019/day15/intcode"
+	"github.com/mbordner/advent_of_code_2019/intcode"
 	"fmt"
 	"strings"
 	"time"
@@ -21,7 +21,7 @@ func main() {
 
 	program := getProgram()
 
-	intCodeComputer := intcode.NewIntCodeComputer(program, in, out, compquit, true)
+	intCodeComputer := intcode.NewIntCodeComputer(program, in, out, compquit, true, nil)
 	gameUI := game.NewGame(gameinput, gameoutput, movecomplete, compquit, quit)
 	gameGraph := graph.NewGraph()
 
@@ -119,6 +119,10 @@ programLoop:
 
 	gameUI.Shutdown()
 
+	if err := intCodeComputer.Err(); err != nil {
+		fmt.Println("program fault: ", err)
+	}
+
 	fmt.Println("fewest number of movement commands required to move the repair droid from its starting position to the location of the oxygen system: ",len(path)-1)
 	fmt.Println("number of minutes it will take to fill the area with oxygen: ",len(generations)-1)
 }
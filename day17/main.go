@@ -2,9 +2,9 @@ package main
 
 import (
 	"github.com/mbordner/advent_of_code_2019/day17/geom"
-	"github.com/mbordner/advent_of_code_2019/day17/intcode"
+	"github.com/mbordner/advent_of_code_2019/intcode"
 	"fmt"
-	"strconv"
+	"io"
 	"strings"
 )
 
@@ -21,49 +21,24 @@ const (
 )
 
 func part1() {
-	in := make(chan string, 1)
-	out := make(chan string, 1)
-	quit := make(chan string, 1)
-
 	program := getProgram()
 
-	intCodeComputer := intcode.NewIntCodeComputer(program, in, out, quit, true)
-
-	go intCodeComputer.Execute()
+	intCodeComputer := intcode.NewIntCodeComputer(program, nil, nil, nil, false, nil)
+	ascii := intcode.NewASCII(intCodeComputer)
 
 	bytes := make([][]byte, 0, 20)
-	row := 0
-	bytes = append(bytes, make([]byte, 0, 60))
 
-programLoop:
 	for {
-		select {
-		case <-in:
-			in <- ""
-
-		case response := <-out:
-
-			val, err := strconv.Atoi(response)
-			if err != nil {
-				panic(err)
-			}
-
-			if val == 10 {
-				row++
-				bytes = append(bytes, make([]byte, 0, 60))
-			} else {
-				b := byte(val)
-				bytes[row] = append(bytes[row], b)
+		line, err := ascii.ReadLine()
+		if err != nil {
+			if err != io.EOF {
+				fmt.Println("program fault: ", err)
 			}
-
-			intCodeComputer.OutputProcessed()
-
-		case <-quit:
-			fmt.Println("program exited")
-			break programLoop
-
+			break
 		}
+		bytes = append(bytes, []byte(line))
 	}
+	fmt.Println("program exited")
 
 	alignmentParameters := make([]geom.Pos, 0, 20)
 
@@ -95,59 +70,36 @@ programLoop:
 }
 
 func part2() {
-	in := make(chan string, 1)
-	out := make(chan string, 1)
-	quit := make(chan string, 1)
-
 	program := getProgram()
 	program[0] = "2"
 
-	intCodeComputer := intcode.NewIntCodeComputer(program, in, out, quit, true)
-
-	go intCodeComputer.Execute()
+	intCodeComputer := intcode.NewIntCodeComputer(program, nil, nil, nil, false, nil)
+	ascii := intcode.NewASCII(intCodeComputer)
 
 	commands := []string{
-		"A,C,A,A,C,B,C,B,B,C\n",
-		"L,12,R,8,L,6,R,8,L,6\n",
-		"L,6,R,6,L,12\n",
-		"R,8,L,12,L,12,R,8\n",
-		"n\n",
+		"A,C,A,A,C,B,C,B,B,C",
+		"L,12,R,8,L,6,R,8,L,6",
+		"L,6,R,6,L,12",
+		"R,8,L,12,L,12,R,8",
+		"n",
 	}
 
-	l := 0
-	for i := range commands {
-		l += len(commands[i])
-	}
-	bytes := make([]byte,0,l)
 	for _, c := range commands {
-		for _, r := range c {
-			bytes = append(bytes,byte(r))
-		}
+		ascii.WriteLine(c)
 	}
 
-programLoop:
 	for {
-		select {
-		case <-in:
-
-			b := bytes[0]
-			bytes = bytes[1:]
-			str := fmt.Sprintf("%d",b)
-			in <- str
-
-		case response := <-out:
-
-			fmt.Println("amount of dust: ", response)
-
-			intCodeComputer.OutputProcessed()
-
-		case <-quit:
-			fmt.Println("program exited")
-			break programLoop
-
+		_, err := ascii.ReadUntilInput()
+		if dust, ok := err.(*intcode.NonASCIIOutput); ok {
+			fmt.Println("amount of dust: ", dust.Text)
+			continue
 		}
+		if err != io.EOF {
+			fmt.Println("program fault: ", err)
+		}
+		break
 	}
-
+	fmt.Println("program exited")
 }
 
 func main() {
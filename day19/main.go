@@ -1,7 +1,7 @@
 package main
 
 import (
-	"github.com/mbordner/advent_of_code_2019/day19/intcode"
+	"github.com/mbordner/advent_of_code_2019/intcode"
 	"bytes"
 	"errors"
 	"fmt"
@@ -12,28 +12,25 @@ import (
 // for each new row y, from 0, find the first x in the row (from 0) where the tractor beam is affecting
 // save that x spot, count to the right, if we are no longer affecting, repeat on next row, otherwise if we reach our limit, count down from saved spot, if we reach our limit, we found the spot, if we stop being affected, advance right and repeat from save that x
 
-func part2() {
-	in := make(chan string, 1)
-	out := make(chan string, 1)
-	quit := make(chan string, 1)
+// probe deploys the drone at x,y and reports whether it is being pulled by the beam
+func probe(c *intcode.IntCodeComputer, x, y int) bool {
+	c.Reset()
+	c.ProvideInput(int64(x), int64(y))
+	status, err := c.RunUntilOutput()
+	if err != nil {
+		panic(err)
+	}
+	return status == intcode.ProducedOutput && c.Output() == 1
+}
 
+func part2() {
 	program := getProgram("program1.txt")
 
-	intCodeComputer := intcode.NewIntCodeComputer(program, in, out, quit, true)
-
-	compute := func(x, y int) (result bool) {
-		intCodeComputer.Reset()
-		go intCodeComputer.Execute()
-		in <- fmt.Sprintf("%d", x)
-		in <- fmt.Sprintf("%d", y)
+	intCodeComputer := intcode.NewIntCodeComputer(program, nil, nil, nil, false, nil)
+	intCodeComputer.SetCompiled(true)
 
-		output := <-out
-		if output == "1" {
-			result = true
-		}
-		intCodeComputer.OutputProcessed()
-		<-quit
-		return
+	compute := func(x, y int) bool {
+		return probe(intCodeComputer, x, y)
 	}
 
 	row := 1000
@@ -67,29 +64,18 @@ outter:
 }
 
 func part1() {
-	in := make(chan string, 1)
-	out := make(chan string, 1)
-	quit := make(chan string, 1)
-
 	program := getProgram("program1.txt")
 
-	intCodeComputer := intcode.NewIntCodeComputer(program, in, out, quit, true)
+	intCodeComputer := intcode.NewIntCodeComputer(program, nil, nil, nil, false, nil)
+	intCodeComputer.SetCompiled(true)
 
 	gameMap := getGameMap(50, 50)
 
 	for y, row := range gameMap {
 		for x := range row {
-			intCodeComputer.Reset()
-			go intCodeComputer.Execute()
-			in <- fmt.Sprintf("%d", x)
-			in <- fmt.Sprintf("%d", y)
-
-			output := <-out
-			if output == "1" {
+			if probe(intCodeComputer, x, y) {
 				gameMap[y][x] = byte('#')
 			}
-			intCodeComputer.OutputProcessed()
-			<-quit
 			fmt.Print(string(gameMap[y][x]))
 		}
 		fmt.Print("\n")
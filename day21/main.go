@@ -1,24 +1,19 @@
 package main
 
 import (
-	"github.com/mbordner/advent_of_code_2019/day21/intcode"
+	"github.com/mbordner/advent_of_code_2019/intcode"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
 	"strings"
 )
 
 func main() {
-	in := make(chan string, 1)
-	out := make(chan string, 1)
-	quit := make(chan string, 1)
-
 	program := getProgram("program.txt")
 
-	intCodeComputer := intcode.NewIntCodeComputer(program, in, out, quit, true)
-
-	go intCodeComputer.Execute()
+	intCodeComputer := intcode.NewIntCodeComputer(program, nil, nil, nil, false, nil)
+	ascii := intcode.NewASCII(intCodeComputer)
 
 	// part 1 commands
 	/*
@@ -41,48 +36,24 @@ NOT A T
 OR T J
 RUN`,"\n")
 
-	l := 0
-	for i := range commands {
-		l += len(commands[i])
-	}
-	bytes := make([]byte,0,l)
 	for _, c := range commands {
-		for _, r := range c {
-			bytes = append(bytes,byte(r))
-		}
-		bytes = append(bytes,byte(10))
+		ascii.WriteLine(c)
 	}
 
-programLoop:
 	for {
-		select {
-		case <-in:
-
-			b := bytes[0]
-			bytes = bytes[1:]
-			str := fmt.Sprintf("%d",b)
-			in <- str
-
-		case response := <-out:
-
-			b, e := strconv.Atoi(response)
-			if e != nil {
-				panic(e)
-			}
-			if b > 127 {
-				fmt.Println(response)
-			} else {
-				fmt.Print(string(byte(b)))
-			}
-
-			intCodeComputer.OutputProcessed()
-
-		case <-quit:
-			fmt.Println("\nprogram exited")
-			break programLoop
-
+		text, err := ascii.ReadUntilInput()
+		fmt.Print(text)
+		if damage, ok := err.(*intcode.NonASCIIOutput); ok {
+			fmt.Println(damage.Text)
+			continue
+		}
+		if err != io.EOF {
+			fmt.Println("program fault: ", err)
 		}
+		break
 	}
+
+	fmt.Println("\nprogram exited")
 }
 
 func getProgram(filename string) []string {
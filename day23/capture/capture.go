@@ -0,0 +1,127 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// Record is a packet sent or delivered on the network.  Time is the scheduler round it was sent or
+// delivered in, and Turn is the id of the computer whose turn it was, or -1 if it was between rounds, so
+// a replay can deliver it at exactly the same point.
+//
+// Every packet is recorded once as it was sent, with Sent set, and once for each copy delivered.  A
+// packet sent to a broadcast address, with Broadcast set, is delivered as copies addressed to each
+// receiver, and Dropped counts the copies that were lost.
+type Record struct {
+	Time      uint64 `json:"time"`
+	Turn      int    `json:"turn"`
+	To        int    `json:"to"`
+	From      int    `json:"from"`
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Sent      bool   `json:"sent,omitempty"`
+	Broadcast bool   `json:"broadcast,omitempty"`
+	Dropped   int    `json:"dropped,omitempty"`
+}
+
+// Before reports whether the packet was sent or delivered before computer id had its turn in round.
+func (r Record) Before(round uint64, id int) bool {
+	return r.Time < round || (r.Time == round && r.Turn < id)
+}
+
+// Writer writes records as JSON, one per line.
+type Writer struct {
+	enc *json.Encoder
+	err error
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{enc: json.NewEncoder(w)}
+}
+
+// Write writes r.  After an error nothing more is written, and the error is returned by Err.
+func (w *Writer) Write(r Record) error {
+	if w.err == nil {
+		w.err = w.enc.Encode(r)
+	}
+	return w.err
+}
+
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// Read reads the records written by a Writer.
+func Read(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// Split separates the records of packets as they were sent from the records of their deliveries.
+func Split(records []Record) (sent, delivered []Record) {
+	for _, r := range records {
+		if r.Sent {
+			sent = append(sent, r)
+		} else {
+			delivered = append(delivered, r)
+		}
+	}
+	return sent, delivered
+}
+
+// Filter returns the records sent from the address from and to the address to.  Either may be -1 to
+// match any address.
+func Filter(records []Record, from, to int) []Record {
+	var filtered []Record
+	for _, r := range records {
+		if (from < 0 || r.From == from) && (to < 0 || r.To == to) {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// Traffic counts the packets sent and received by an address.
+type Traffic struct {
+	Addr     int
+	Sent     int
+	Received int
+}
+
+// Count returns the traffic of every address that appears in records, by address.  Packets are counted
+// as sent from their Sent records, and as received from their deliveries.
+func Count(records []Record) []Traffic {
+	counts := make(map[int]*Traffic)
+	get := func(addr int) *Traffic {
+		if counts[addr] == nil {
+			counts[addr] = &Traffic{Addr: addr}
+		}
+		return counts[addr]
+	}
+	for _, r := range records {
+		if r.Sent {
+			get(r.From).Sent++
+		} else {
+			get(r.To).Received++
+		}
+	}
+	traffic := make([]Traffic, 0, len(counts))
+	for _, t := range counts {
+		traffic = append(traffic, *t)
+	}
+	sort.Slice(traffic, func(i, j int) bool { return traffic[i].Addr < traffic[j].Addr })
+	return traffic
+}
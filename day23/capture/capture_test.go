@@ -0,0 +1,50 @@
+package capture
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Capture(t *testing.T) {
+	records := []Record{
+		{Time: 0, Turn: 1, To: 3, From: 1, X: 10, Y: 20, Sent: true},
+		{Time: 0, Turn: 1, To: 3, From: 1, X: 10, Y: 20},
+		{Time: 0, Turn: 3, To: 255, From: 3, X: 11, Y: 21, Sent: true},
+		{Time: 0, Turn: 3, To: 255, From: 3, X: 11, Y: 21},
+		{Time: 1, Turn: -1, To: 0, From: 255, X: 11, Y: 21, Sent: true},
+		{Time: 1, Turn: -1, To: 0, From: 255, X: 11, Y: 21},
+		{Time: 2, Turn: 0, To: 100, From: 0, X: 12, Y: 22, Sent: true, Broadcast: true, Dropped: 1},
+		{Time: 2, Turn: 0, To: 3, From: 0, X: 12, Y: 22},
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, r := range records {
+		assert.Nil(t, w.Write(r))
+	}
+	read, err := Read(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, records, read)
+
+	sent, delivered := Split(records)
+	assert.Equal(t, []Record{records[0], records[2], records[4], records[6]}, sent)
+	assert.Equal(t, []Record{records[1], records[3], records[5], records[7]}, delivered)
+
+	assert.Equal(t, []Record{records[1], records[7]}, Filter(delivered, -1, 3))
+	assert.Equal(t, []Record{records[7]}, Filter(delivered, 0, 3))
+	assert.Equal(t, []Record{records[6]}, Filter(sent, 0, -1))
+	assert.Equal(t, records, Filter(records, -1, -1))
+
+	assert.Equal(t, []Traffic{
+		{Addr: 0, Sent: 1, Received: 1},
+		{Addr: 1, Sent: 1},
+		{Addr: 3, Sent: 1, Received: 2},
+		{Addr: 255, Sent: 1, Received: 1},
+	}, Count(records))
+
+	assert.True(t, records[1].Before(0, 2))
+	assert.False(t, records[1].Before(0, 1))
+	assert.True(t, records[1].Before(1, 0))
+	assert.True(t, records[5].Before(1, 0))
+}
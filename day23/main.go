@@ -1,15 +1,16 @@
 package main
 
 import (
+	"bufio"
 	"errors"
+	"flag"
 	"fmt"
+	"github.com/mbordner/advent_of_code_2019/day23/capture"
 	"github.com/mbordner/advent_of_code_2019/day23/geom"
-	"github.com/mbordner/advent_of_code_2019/day23/intcode"
+	"github.com/mbordner/advent_of_code_2019/intcode"
+	"log"
 	"os"
-	"strconv"
 	"strings"
-	"sync"
-	"time"
 )
 
 type Packet struct {
@@ -31,231 +32,258 @@ func NewPacket(to, from, x, y int) *Packet {
 	return &p
 }
 
+const NAT_ADDRESS = 255
+
+// ErrNetworkStalled is returned when the network goes idle before any packet reaches the NAT, so it has
+// nothing to send to computer 0 and the network would stay idle for good.
+var ErrNetworkStalled = errors.New("network stalled: idle with no packet for the NAT to send")
+
+// Nat remembers the last packet sent to address 255, and sends it to computer 0 whenever the network
+// goes quiet.
 type Nat struct {
-	lastPacket *Packet
-	quit       chan bool
-	computers []*Computer
+	firstPacket *Packet
+	lastPacket  *Packet
+	lastSent    *Packet
+	repeated    *Packet // the first packet sent to computer 0 twice in a row
+	err         error
+	computers   []*Computer
+	network     *Switch
 }
 
-func NewNat(computers []*Computer) *Nat {
+func NewNat(computers []*Computer, network *Switch) *Nat {
 	n := new(Nat)
 	n.computers = computers
-	go n.loop()
+	n.network = network
 	return n
 }
 
-func (n *Nat) Halt() {
-	n.quit <- true
+func (n *Nat) Receive(p *Packet) {
+	if n.firstPacket == nil {
+		n.firstPacket = p
+	}
+	n.lastPacket = p
 }
 
-func (n *Nat) loop() {
-	tick := time.Tick(time.Duration(1) * time.Second)
-
-	const IDLE_THRESHOLD = 5
-
-programLoop:
-	for {
-		select {
-		case <-tick:
-			if n.lastPacket != nil {
-				idle := true
-				for i:= range n.computers {
-					if n.computers[i].GetIdleCount() < IDLE_THRESHOLD {
-						idle = false
-						break
-					}
-				}
-				if idle {
-					fmt.Printf(">>>>> sending idle packet %s\n",n.lastPacket)
-					n.computers[0].Receive(n.lastPacket)
-				}
-			}
-		case <-n.quit:
-			break programLoop
+// Idle reports whether the network is quiet: every computer is waiting for packets, all their queues
+// are empty, none is part way through sending a packet, and no packets are in flight.
+func (n *Nat) Idle() bool {
+	if n.network.InFlight() > 0 {
+		return false
+	}
+	for _, c := range n.computers {
+		if !c.Idle() {
+			return false
 		}
 	}
+	return true
 }
 
-func (n *Nat) Receive(p *Packet) {
-	n.lastPacket = p
+// Check resends the last packet to computer 0 if the network is idle.  It stops the scheduler once the
+// same Y value is sent twice in a row, or with ErrNetworkStalled if there is no packet to resend.
+func (n *Nat) Check(s *intcode.Scheduler) {
+	if !n.Idle() {
+		return
+	}
+	if n.lastPacket == nil {
+		n.err = ErrNetworkStalled
+		s.Stop()
+		return
+	}
+	p := NewPacket(0, NAT_ADDRESS, n.lastPacket.X, n.lastPacket.Y)
+	fmt.Printf("network idle after %d rounds, NAT sends y=%d\n", s.Round(), p.Y)
+	if n.lastSent != nil && n.lastSent.Y == p.Y {
+		n.repeated = p
+		s.Stop()
+		return
+	}
+	n.lastSent = p
+	n.network.Send(p)
 }
 
 type Computer struct {
-	ID              int
-	program         []string
-	intCodeComputer *intcode.IntCodeComputer
-	inputQueue      []int
-	outputQueue     []int
-	networkOut      chan *Packet
-	in              chan string
-	out             chan string
-	quit            chan string
-	wg              *sync.WaitGroup
-	mux             sync.Mutex
-	idSent          bool
-	idleCount       int
-	running bool
+	ID          int
+	task        *intcode.Task
+	inputQueue  []int
+	outputQueue []int
+	route       func(p *Packet)
+	idSent      bool
+	polled      bool // the last input was -1, and nothing was received or sent since
+	idle        bool // it polled again, so it is waiting for packets
 }
 
-func NewComputer(id int) *Computer {
+func NewComputer(id int, route func(p *Packet)) *Computer {
 	c := new(Computer)
 
 	c.ID = id
-
-	c.in = make(chan string, 1)
-	c.out = make(chan string, 1)
-	c.quit = make(chan string, 1)
-
-	c.networkOut = make(chan *Packet, 25)
+	c.route = route
 
 	c.inputQueue = make([]int, 0, 25)
 	c.outputQueue = make([]int, 0, 3)
 
-
 	return c
 }
 
-func (c *Computer) GetIdleCount() int {
-	return c.idleCount
+// Start adds the computer running program to the scheduler.
+func (c *Computer) Start(s *intcode.Scheduler, program []string) {
+	c.task = s.Add(intcode.NewIntCodeComputer(program, nil, nil, nil, false, nil), c.input, c.output)
 }
 
-func (c *Computer) Execute(p []string, wg *sync.WaitGroup) {
-	c.wg = wg
-	c.program = make([]string, len(p), len(p))
-	copy(c.program, p)
-	prompt := "ready"
-	c.intCodeComputer = intcode.NewIntCodeComputer(c.program, c.in, c.out, c.quit, true, &prompt)
-	go c.loop()
-	go c.intCodeComputer.Execute()
+func (c *Computer) Idle() bool {
+	return c.idle && len(c.inputQueue) == 0 && len(c.outputQueue) == 0
 }
 
 func (c *Computer) Receive(p *Packet) {
-	if c.running {
-		c.mux.Lock()
-		c.inputQueue = append(c.inputQueue, p.X)
-		c.inputQueue = append(c.inputQueue, p.Y)
-		c.mux.Unlock()
-	}
+	c.inputQueue = append(c.inputQueue, p.X)
+	c.inputQueue = append(c.inputQueue, p.Y)
 }
 
-func (c *Computer) GetNetworkOutputChannel() <-chan *Packet {
-	return c.networkOut
+func (c *Computer) input(t *intcode.Task) {
+	if !c.idSent {
+		// first input must be the network id
+		c.idSent = true
+		t.Send(int64(c.ID))
+		return
+	}
+	if len(c.inputQueue) > 0 {
+		p := c.inputQueue[0]
+		c.inputQueue = c.inputQueue[1:]
+		c.polled, c.idle = false, false
+		t.Send(int64(p))
+		return
+	}
+	c.idle = c.polled
+	c.polled = true
+	t.Send(-1) // no packets available
 }
 
-func (c *Computer) loop() {
-	c.running = true
-
-programLoop:
-	for {
-		select {
-		case prompt := <-c.intCodeComputer.GetPromptChannel():
-			if prompt == "ready" {
-				c.mux.Lock()
-
-				if c.idSent {
-
-					if len(c.inputQueue) > 0 {
-
-						p := c.inputQueue[0]
-						c.inputQueue = c.inputQueue[1:]
-						c.idleCount = 0
-						c.mux.Unlock()
-						c.in <- fmt.Sprintf("%d", p)
-
-					} else {
-						c.idleCount++
-						c.mux.Unlock()
-						c.in <- "-1" // no packets available
-					}
-				} else {
-					// first input must be the network id
-					c.idSent = true
-					c.in <- fmt.Sprintf("%d", c.ID)
-					c.mux.Unlock()
-
-				}
-			}
-		case p := <-c.out:
-
-			c.mux.Lock()
-
-			i, e := strconv.Atoi(p)
-			if e != nil {
-				panic(e)
-			}
-
-			c.outputQueue = append(c.outputQueue, i)
-
-			if len(c.outputQueue) == 3 {
-				to := c.outputQueue[0]
-				x := c.outputQueue[1]
-				y := c.outputQueue[2]
-				c.outputQueue = make([]int, 0, 3)
-				packet := NewPacket(to, c.ID, x, y)
-				c.networkOut <- packet
-			}
-
-			c.mux.Unlock()
-
-			c.intCodeComputer.OutputProcessed()
+func (c *Computer) output(t *intcode.Task, value int64) {
+	c.polled, c.idle = false, false
 
+	c.outputQueue = append(c.outputQueue, int(value))
 
-		case <-c.quit:
-			fmt.Printf("computer %d shutting down\n", c.ID)
-			break programLoop
-
-		}
+	if len(c.outputQueue) == 3 {
+		packet := NewPacket(c.outputQueue[0], c.ID, c.outputQueue[1], c.outputQueue[2])
+		c.outputQueue = c.outputQueue[:0]
+		c.route(packet)
 	}
-
-	c.running = false
-
-	close(c.in)
-	close(c.out)
-	close(c.quit)
-
-	close(c.networkOut)
 }
 
+// usage: day23 [-computers n] [-capture packets.jsonl] [-topology mesh|ring|star] [-latency ticks]
+//              [-drop rate] [-reorder rate] [-seed n] [-broadcast addr] [-monitor] [-rounds n]
+//        day23 -view packets.jsonl [-from addr] [-to addr]
+//        day23 -replay packets.jsonl -node addr
+//
+// The switch options simulate a network other than the puzzle's, where packets are delivered at once.
+// Drops and reordering are chosen with a random source seeded by -seed, so a run can be repeated.
 func main() {
-	program := getProgram("program.txt")
+	computers := flag.Int("computers", 50, "number of computers on the network")
+	capturePath := flag.String("capture", "", "file to record every packet sent and delivered on the network to")
+	view := flag.String("view", "", "capture file to print the packets and traffic of")
+	from := flag.Int("from", -1, "with -view, only packets from this address")
+	to := flag.Int("to", -1, "with -view, only packets to this address")
+	replay := flag.String("replay", "", "capture file to drive a single computer from")
+	node := flag.Int("node", 0, "with -replay, the address of the computer to run")
+	topology := flag.String("topology", "mesh", "how the computers are linked: mesh, ring or star")
+	latency := flag.Uint64("latency", 0, "scheduler ticks a packet takes to cross a link")
+	drop := flag.Float64("drop", 0, "chance a packet is lost")
+	reorder := flag.Float64("reorder", 0, "chance a packet is held back so later ones overtake it")
+	seed := flag.Int64("seed", 1, "seed for drops and reordering")
+	broadcast := flag.Int("broadcast", -1, "address that delivers packets to every computer")
+	monitor := flag.Bool("monitor", false, "print every packet delivered")
+	rounds := flag.Uint64("rounds", 0, "stop after this many scheduler rounds, if not 0")
+	flag.Parse()
+
+	var err error
+	switch {
+	case *view != "":
+		err = viewCapture(*view, *from, *to)
+	case *replay != "":
+		err = replayCapture(getProgram("program.txt"), *replay, *node)
+	default:
+		config := SwitchConfig{Latency: *latency, DropRate: *drop, ReorderRate: *reorder, ReorderDelay: 5, Seed: *seed}
+		if *broadcast >= 0 {
+			config.Broadcast = []int{*broadcast}
+		}
+		config.Topology, err = NewTopology(*topology, *computers)
+		if err == nil {
+			err = run(getProgram("program.txt"), config, *computers, *capturePath, *monitor, *rounds)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
 
-	var wg sync.WaitGroup
+// run boots count computers running program on a switch configured by config.
+func run(program []string, config SwitchConfig, count int, capturePath string, monitor bool, rounds uint64) error {
+	if count < 1 || count > NAT_ADDRESS {
+		return fmt.Errorf("the number of computers must be from 1 to %d", NAT_ADDRESS)
+	}
+	s := intcode.NewScheduler(0)
+	network := NewSwitch(config, s.Round)
 
-	const NUM_COMPUTERS = 50
+	if capturePath != "" {
+		file, err := os.Create(capturePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w := bufio.NewWriter(file)
+		defer w.Flush()
+		recorder := capture.NewWriter(w)
+		defer func() {
+			if recorder.Err() != nil {
+				log.Println(recorder.Err())
+			}
+		}()
+		network.MonitorSent(func(sent Sent) {
+			recorder.Write(recordSent(s, sent))
+		})
+		network.Monitor(func(p *Packet) {
+			recorder.Write(record(s, p))
+		})
+	}
+	if monitor {
+		network.Monitor(func(p *Packet) {
+			fmt.Printf("%6d %s\n", s.Round(), p)
+		})
+	}
 
-	computers := make([]*Computer, NUM_COMPUTERS, NUM_COMPUTERS)
-	netout := make([]<-chan *Packet, NUM_COMPUTERS, NUM_COMPUTERS)
+	computers := make([]*Computer, count, count)
+	nat := NewNat(computers, network)
+	network.Attach(NAT_ADDRESS, nat.Receive)
 
-	for i := 0; i < NUM_COMPUTERS; i++ {
-		computers[i] = NewComputer(i)
-		wg.Add(1)
+	s.OnRound = func(s *intcode.Scheduler) {
+		network.Tick()
+		nat.Check(s)
+		if rounds > 0 && s.Round() >= rounds {
+			s.Stop()
+		}
 	}
 
 	for i := range computers {
-		netout[i] = computers[i].GetNetworkOutputChannel()
-		computers[i].Execute(program, &wg)
+		computers[i] = NewComputer(i, network.Send)
+		computers[i].Start(s, program)
+		network.Attach(i, computers[i].Receive)
 	}
 
-	nat := NewNat(computers)
-
-	for _, no := range netout {
-		go func(no <-chan *Packet) {
-			for p := range no {
-				//fmt.Printf("incoming packet: %s\n", p)
-				if p.To >= 0 && p.To < len(computers) {
-					computers[p.To].Receive(p)
-				} else if p.To == 255 {
-					nat.Receive(p)
-				}
-			}
-		}(no)
+	if err := s.Run(); err != nil {
+		return err
+	}
+	if nat.err != nil {
+		return fmt.Errorf("round %d: %w", s.Round(), nat.err)
 	}
 
-	nat.Halt()
-
-	wg.Wait()
-
-	fmt.Println("all computers shutdown.")
+	stats := network.Stats()
+	fmt.Printf("%d rounds, %d packets sent, %d delivered, %d dropped, %d reordered, %d unroutable\n",
+		s.Round(), stats.Sent, stats.Delivered, stats.Dropped, stats.Reordered, stats.Unroutable)
+	if nat.firstPacket != nil {
+		fmt.Println("part 1, first y sent to the NAT:", nat.firstPacket.Y)
+	}
+	if nat.repeated != nil {
+		fmt.Println("part 2, first y sent by the NAT twice in a row:", nat.repeated.Y)
+	}
+	return nil
 }
 
 func getProgram(filename string) []string {
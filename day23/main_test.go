@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"github.com/mbordner/advent_of_code_2019/intcode"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// computer 0 broadcasts to address 100, then every computer answers each packet (x, y) with a packet
+// (its id, y+1) sent to address x
+const testNIC = `
+        IN   [id]
+        JNZ  [id], #poll
+        OUT  #100
+        OUT  #0
+        OUT  #1
+poll:   IN   [x]
+        EQ   [x], #-1, [t]
+        JNZ  [t], #poll
+        IN   [y]
+        ADD  [y], #1, [y]
+        OUT  [x]
+        OUT  [id]
+        OUT  [y]
+        JZ   #0, #poll
+id:     DATA 0
+x:      DATA 0
+y:      DATA 0
+t:      DATA 0
+`
+
+func testProgram(t *testing.T) []string {
+	assembled, err := intcode.Assemble(testNIC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return assembled.Program
+}
+
+func Test_NetworkStalled(t *testing.T) {
+	program := testProgram(t)
+
+	// the broadcast is lost, so nothing ever reaches the NAT
+	err := run(program, SwitchConfig{Broadcast: []int{100}, DropRate: 1}, 3, "", false, 1000)
+	assert.True(t, errors.Is(err, ErrNetworkStalled), "%v", err)
+
+	assert.Nil(t, run(program, SwitchConfig{Broadcast: []int{100}}, 3, "", false, 1000))
+
+	assert.NotNil(t, run(program, SwitchConfig{}, 0, "", false, 1000))
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// Link connects two addresses.  A is always the lower one.
+type Link struct {
+	A int
+	B int
+}
+
+func NewLink(a, b int) Link {
+	if a > b {
+		a, b = b, a
+	}
+	return Link{A: a, B: b}
+}
+
+// Topology decides which links a packet crosses between two computers.  Addresses outside the topology,
+// such as the NAT's, are attached to the switch directly, so their packets cross a single link.
+type Topology interface {
+	Route(from, to int) []Link
+}
+
+// FullMesh links every computer to every other one.
+type FullMesh struct{}
+
+func (FullMesh) Route(from, to int) []Link {
+	return []Link{NewLink(from, to)}
+}
+
+// Ring links each of Size computers to its two neighbours.  Packets go the shorter way around.
+type Ring struct {
+	Size int
+}
+
+func (r Ring) Route(from, to int) []Link {
+	if from < 0 || from >= r.Size || to < 0 || to >= r.Size {
+		return []Link{NewLink(from, to)}
+	}
+	step := 1
+	if (to-from+r.Size)%r.Size > r.Size/2 {
+		step = r.Size - 1
+	}
+	var links []Link
+	for at := from; at != to; {
+		next := (at + step) % r.Size
+		links = append(links, NewLink(at, next))
+		at = next
+	}
+	return links
+}
+
+// Star links each of Size computers to the Hub computer only, so packets between two other computers
+// are relayed through it.
+type Star struct {
+	Hub  int
+	Size int
+}
+
+func (s Star) Route(from, to int) []Link {
+	if from == s.Hub || to == s.Hub || from < 0 || from >= s.Size || to < 0 || to >= s.Size {
+		return []Link{NewLink(from, to)}
+	}
+	return []Link{NewLink(from, s.Hub), NewLink(s.Hub, to)}
+}
+
+// NewTopology returns the topology called name for size computers: mesh, ring or star, whose hub is
+// computer 0.
+func NewTopology(name string, size int) (Topology, error) {
+	switch name {
+	case "mesh":
+		return FullMesh{}, nil
+	case "ring":
+		return Ring{Size: size}, nil
+	case "star":
+		return Star{Hub: 0, Size: size}, nil
+	}
+	return nil, fmt.Errorf("unknown topology %s", name)
+}
+
+type SwitchConfig struct {
+	Topology Topology
+	// Latency is the number of scheduler ticks a packet takes to cross a link, unless LinkLatency has
+	// an entry for the link
+	Latency     uint64
+	LinkLatency map[Link]uint64
+	// DropRate is the chance a packet is lost
+	DropRate float64
+	// ReorderRate is the chance a packet is held for up to ReorderDelay extra ticks, so packets sent
+	// after it can overtake it
+	ReorderRate  float64
+	ReorderDelay uint64
+	// Seed seeds the drops and reordering, so a run can be repeated exactly
+	Seed int64
+	// Broadcast addresses deliver a copy of a packet to every attached address but the sender's
+	Broadcast []int
+}
+
+type SwitchStats struct {
+	Sent       int
+	Delivered  int
+	Dropped    int
+	Reordered  int
+	Unroutable int
+}
+
+// Sent describes a packet as it was sent.  Broadcast is set if it was sent to a broadcast address, and
+// Dropped is the number of copies of it that were lost, which is at most 1 for other packets.
+type Sent struct {
+	*Packet
+	Broadcast bool
+	Dropped   int
+}
+
+type inFlight struct {
+	at  uint64
+	seq int
+	p   *Packet
+}
+
+// Switch delivers packets between the addresses attached to it, simulating latency, loss and
+// reordering.  Time is measured in scheduler ticks, read from clock, and packets that are due are
+// delivered by Tick.
+type Switch struct {
+	config    SwitchConfig
+	clock     func() uint64
+	rand      *rand.Rand
+	endpoints map[int]func(p *Packet)
+	monitors  []func(p *Packet)
+	senders   []func(sent Sent)
+	pending   []inFlight
+	seq       int
+	stats     SwitchStats
+}
+
+func NewSwitch(config SwitchConfig, clock func() uint64) *Switch {
+	if config.Topology == nil {
+		config.Topology = FullMesh{}
+	}
+	if config.ReorderDelay == 0 {
+		config.ReorderDelay = 1
+	}
+	return &Switch{
+		config:    config,
+		clock:     clock,
+		rand:      rand.New(rand.NewSource(config.Seed)),
+		endpoints: make(map[int]func(p *Packet)),
+	}
+}
+
+// Attach delivers packets sent to addr to receive.
+func (s *Switch) Attach(addr int, receive func(p *Packet)) {
+	s.endpoints[addr] = receive
+}
+
+// Monitor calls fn with every packet the switch delivers, before it is delivered.
+func (s *Switch) Monitor(fn func(p *Packet)) {
+	s.monitors = append(s.monitors, fn)
+}
+
+// MonitorSent calls fn with every packet sent to the switch, including the ones it drops, before any
+// of it is delivered.
+func (s *Switch) MonitorSent(fn func(sent Sent)) {
+	s.senders = append(s.senders, fn)
+}
+
+func (s *Switch) Stats() SwitchStats {
+	return s.stats
+}
+
+// InFlight returns the number of packets sent but not yet delivered.
+func (s *Switch) InFlight() int {
+	return len(s.pending)
+}
+
+func (s *Switch) isBroadcast(addr int) bool {
+	for _, b := range s.config.Broadcast {
+		if b == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// Send routes p, delivering it at once if it has no latency.
+func (s *Switch) Send(p *Packet) {
+	s.stats.Sent++
+	sent := Sent{Packet: p, Broadcast: s.isBroadcast(p.To)}
+	packets := []*Packet{p}
+	if sent.Broadcast {
+		addrs := make([]int, 0, len(s.endpoints))
+		for addr := range s.endpoints {
+			if addr != p.From {
+				addrs = append(addrs, addr)
+			}
+		}
+		sort.Ints(addrs)
+		packets = packets[:0]
+		for _, addr := range addrs {
+			packets = append(packets, NewPacket(addr, p.From, p.X, p.Y))
+		}
+	}
+
+	// every copy is routed before the monitors are told how many were dropped, and only then delivered
+	latencies := make([]uint64, len(packets))
+	routed := packets[:0]
+	for _, c := range packets {
+		latency, ok := s.route(c)
+		if !ok {
+			sent.Dropped++
+			continue
+		}
+		latencies[len(routed)] = latency
+		routed = append(routed, c)
+	}
+	for _, m := range s.senders {
+		m(sent)
+	}
+	for i, c := range routed {
+		s.schedule(c, latencies[i])
+	}
+}
+
+// route decides how many ticks p takes to arrive, or that it is dropped.
+func (s *Switch) route(p *Packet) (uint64, bool) {
+	if s.config.DropRate > 0 && s.rand.Float64() < s.config.DropRate {
+		s.stats.Dropped++
+		return 0, false
+	}
+	var latency uint64
+	for _, l := range s.config.Topology.Route(p.From, p.To) {
+		if v, ok := s.config.LinkLatency[l]; ok {
+			latency += v
+		} else {
+			latency += s.config.Latency
+		}
+	}
+	if s.config.ReorderRate > 0 && s.rand.Float64() < s.config.ReorderRate {
+		s.stats.Reordered++
+		latency += 1 + uint64(s.rand.Int63n(int64(s.config.ReorderDelay)))
+	}
+	return latency, true
+}
+
+func (s *Switch) schedule(p *Packet, latency uint64) {
+	if latency == 0 {
+		s.deliver(p)
+		return
+	}
+	f := inFlight{at: s.clock() + latency, seq: s.seq, p: p}
+	s.seq++
+	i := sort.Search(len(s.pending), func(i int) bool { return s.pending[i].at > f.at })
+	s.pending = append(s.pending, inFlight{})
+	copy(s.pending[i+1:], s.pending[i:])
+	s.pending[i] = f
+}
+
+// Tick delivers the packets that are due.
+func (s *Switch) Tick() {
+	now := s.clock()
+	n := 0
+	for n < len(s.pending) && s.pending[n].at <= now {
+		n++
+	}
+	due := s.pending[:n]
+	s.pending = append([]inFlight(nil), s.pending[n:]...)
+	for _, f := range due {
+		s.deliver(f.p)
+	}
+}
+
+func (s *Switch) deliver(p *Packet) {
+	receive := s.endpoints[p.To]
+	if receive == nil {
+		s.stats.Unroutable++
+		return
+	}
+	s.stats.Delivered++
+	for _, m := range s.monitors {
+		m(p)
+	}
+	receive(p)
+}
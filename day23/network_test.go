@@ -0,0 +1,106 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Topologies(t *testing.T) {
+	assert.Equal(t, []Link{{2, 7}}, FullMesh{}.Route(7, 2))
+
+	ring := Ring{Size: 6}
+	assert.Equal(t, []Link{{1, 2}, {2, 3}}, ring.Route(1, 3))
+	assert.Equal(t, []Link{{1, 2}, {2, 3}, {3, 4}}, ring.Route(1, 4))
+	assert.Equal(t, []Link{{0, 5}, {0, 1}}, ring.Route(5, 1))
+	assert.Equal(t, []Link{{3, 255}}, ring.Route(3, 255))
+
+	star := Star{Hub: 0, Size: 6}
+	assert.Equal(t, []Link{{0, 3}}, star.Route(0, 3))
+	assert.Equal(t, []Link{{0, 2}, {0, 3}}, star.Route(2, 3))
+	assert.Equal(t, []Link{{2, 255}}, star.Route(2, 255))
+
+	_, err := NewTopology("bus", 6)
+	assert.NotNil(t, err)
+}
+
+type testNetwork struct {
+	now      uint64
+	received []string
+	sw       *Switch
+}
+
+func newTestNetwork(config SwitchConfig, addrs ...int) *testNetwork {
+	n := new(testNetwork)
+	n.sw = NewSwitch(config, func() uint64 { return n.now })
+	for _, addr := range addrs {
+		n.sw.Attach(addr, func(p *Packet) {
+			n.received = append(n.received, p.String())
+		})
+	}
+	return n
+}
+
+func (n *testNetwork) run(ticks uint64) {
+	for i := uint64(0); i < ticks; i++ {
+		n.now++
+		n.sw.Tick()
+	}
+}
+
+func Test_SwitchLatency(t *testing.T) {
+	n := newTestNetwork(SwitchConfig{
+		Topology:    Ring{Size: 4},
+		Latency:     2,
+		LinkLatency: map[Link]uint64{{0, 1}: 5},
+	}, 0, 1, 2, 3)
+
+	n.sw.Send(NewPacket(2, 1, 10, 20)) // one link of 2 ticks
+	n.sw.Send(NewPacket(1, 0, 11, 21)) // one slow link
+	n.sw.Send(NewPacket(9, 0, 12, 22)) // nobody attached
+	assert.Equal(t, 3, n.sw.InFlight())
+
+	n.run(1)
+	assert.Nil(t, n.received)
+	n.run(1)
+	assert.Equal(t, []string{NewPacket(2, 1, 10, 20).String()}, n.received)
+	n.run(3)
+	assert.Equal(t, 2, len(n.received))
+	assert.Equal(t, 0, n.sw.InFlight())
+	assert.Equal(t, SwitchStats{Sent: 3, Delivered: 2, Unroutable: 1}, n.sw.Stats())
+}
+
+func Test_SwitchBroadcast(t *testing.T) {
+	n := newTestNetwork(SwitchConfig{Broadcast: []int{100}}, 0, 1, 2, 255)
+	n.sw.Send(NewPacket(100, 1, 3, 4))
+	assert.Equal(t, []string{
+		NewPacket(0, 1, 3, 4).String(),
+		NewPacket(2, 1, 3, 4).String(),
+		NewPacket(255, 1, 3, 4).String(),
+	}, n.received)
+}
+
+func Test_SwitchFaults(t *testing.T) {
+	send := func(seed int64) ([]string, SwitchStats) {
+		n := newTestNetwork(SwitchConfig{Latency: 1, DropRate: 0.2, ReorderRate: 0.3, ReorderDelay: 4, Seed: seed}, 0, 1)
+		var monitored int
+		n.sw.Monitor(func(p *Packet) { monitored++ })
+		for i := 0; i < 50; i++ {
+			n.sw.Send(NewPacket(i%2, 1-i%2, i, i))
+			n.run(1)
+		}
+		n.run(10)
+		assert.Equal(t, len(n.received), monitored)
+		return n.received, n.sw.Stats()
+	}
+
+	received, stats := send(5)
+	again, againStats := send(5)
+	assert.Equal(t, received, again)
+	assert.Equal(t, stats, againStats)
+	assert.True(t, stats.Dropped > 0)
+	assert.True(t, stats.Reordered > 0)
+	assert.Equal(t, stats.Sent, stats.Delivered+stats.Dropped)
+
+	other, _ := send(6)
+	assert.NotEqual(t, received, other)
+}
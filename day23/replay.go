@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mbordner/advent_of_code_2019/day23/capture"
+	"github.com/mbordner/advent_of_code_2019/intcode"
+	"os"
+)
+
+// record describes p as it is delivered during the scheduler's current turn.
+func record(s *intcode.Scheduler, p *Packet) capture.Record {
+	turn := -1
+	if t := s.Current(); t != nil {
+		turn = t.ID
+	}
+	return capture.Record{Time: s.Round(), Turn: turn, To: p.To, From: p.From, X: p.X, Y: p.Y}
+}
+
+// recordSent describes a packet as it is sent during the scheduler's current turn.
+func recordSent(s *intcode.Scheduler, sent Sent) capture.Record {
+	r := record(s, sent.Packet)
+	r.Sent, r.Broadcast, r.Dropped = true, sent.Broadcast, sent.Dropped
+	return r
+}
+
+func readCapture(filename string) ([]capture.Record, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return capture.Read(file)
+}
+
+func viewCapture(filename string, from, to int) error {
+	records, err := readCapture(filename)
+	if err != nil {
+		return err
+	}
+	records = capture.Filter(records, from, to)
+	for _, r := range records {
+		event := "delivered"
+		if r.Sent {
+			event = "sent"
+			if r.Broadcast {
+				event = "broadcast"
+			}
+			if r.Dropped > 0 {
+				event += fmt.Sprintf(", %d lost", r.Dropped)
+			}
+		}
+		fmt.Printf("%6d %3d -> %3d  x:%d y:%d  %s\n", r.Time, r.From, r.To, r.X, r.Y, event)
+	}
+	fmt.Printf("\n%d records\n", len(records))
+	fmt.Println("\naddr   sent received")
+	for _, t := range capture.Count(records) {
+		fmt.Printf("%4d %6d %8d\n", t.Addr, t.Sent, t.Received)
+	}
+	return nil
+}
+
+// replayCapture runs the computer with address id alone, delivering the packets the capture shows it
+// received at the same points they were delivered in the recorded run, and compares the packets it sends
+// with the ones the capture shows it sent, including the ones the switch dropped.
+func replayCapture(program []string, filename string, id int) error {
+	records, err := readCapture(filename)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%s has no packets", filename)
+	}
+	sent, delivered := capture.Split(records)
+	inbound := capture.Filter(delivered, -1, id)
+	expected := capture.Filter(sent, id, -1)
+	end := records[len(records)-1].Time
+
+	// packets are captured as they are sent, so the computer must send them in the same order
+	count := 0
+	mismatch := -1
+	c := NewComputer(id, func(p *Packet) {
+		status := "not in the capture"
+		if len(expected) > 0 {
+			if r := expected[0]; r.To == p.To && r.X == p.X && r.Y == p.Y {
+				expected = expected[1:]
+				status = "ok"
+			} else {
+				status = fmt.Sprintf("captured as {to:%d, x:%d, y:%d}", r.To, r.X, r.Y)
+			}
+		}
+		if status != "ok" && mismatch < 0 {
+			mismatch = count
+		}
+		count++
+		fmt.Printf("sent %s %s\n", p, status)
+	})
+
+	s := intcode.NewScheduler(0)
+	deliver := func(s *intcode.Scheduler) {
+		for len(inbound) > 0 && inbound[0].Before(s.Round(), id) {
+			r := inbound[0]
+			inbound = inbound[1:]
+			p := NewPacket(r.To, r.From, r.X, r.Y)
+			fmt.Printf("received %s\n", p)
+			c.Receive(p)
+		}
+		if s.Round() > end || mismatch >= 0 {
+			s.Stop()
+		}
+	}
+	s.OnRound = deliver
+	c.Start(s, program)
+	deliver(s)
+	if err := s.Run(); err != nil {
+		return err
+	}
+
+	switch {
+	case mismatch >= 0:
+		return fmt.Errorf("packet %d differs from the capture", mismatch)
+	case len(expected) > 0:
+		return fmt.Errorf("%d captured packets were not sent", len(expected))
+	}
+	fmt.Printf("all %d packets match the capture\n", count)
+	return nil
+}
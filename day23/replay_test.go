@@ -0,0 +1,78 @@
+package main
+
+import (
+	"github.com/mbordner/advent_of_code_2019/day23/capture"
+	"github.com/mbordner/advent_of_code_2019/intcode"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Record(t *testing.T) {
+	s := intcode.NewScheduler(0)
+	p := NewPacket(100, 1, 10, 20)
+	assert.Equal(t, capture.Record{Time: 0, Turn: -1, To: 100, From: 1, X: 10, Y: 20}, record(s, p))
+
+	var recorded capture.Record
+	s.Add(intcode.NewIntCodeComputer(strings.Split(`99`, ","), nil, nil, nil, false, nil), nil, nil)
+	s.Add(intcode.NewIntCodeComputer(strings.Split(`104,0,99`, ","), nil, nil, nil, false, nil), nil,
+		func(t *intcode.Task, value int64) {
+			recorded = recordSent(s, Sent{Packet: p, Broadcast: true, Dropped: 2})
+		})
+	assert.Nil(t, s.Run())
+	assert.Equal(t, capture.Record{Time: 0, Turn: 1, To: 100, From: 1, X: 10, Y: 20, Sent: true, Broadcast: true,
+		Dropped: 2}, recorded)
+}
+
+func Test_ReplayCapture(t *testing.T) {
+	program := testProgram(t)
+	dir, err := ioutil.TempDir("", "day23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "packets.jsonl")
+
+	config := SwitchConfig{Broadcast: []int{100}, Latency: 1, DropRate: 0.2, ReorderRate: 0.2, Seed: 3}
+	assert.Nil(t, run(program, config, 3, filename, false, 300))
+	records, err := readCapture(filename)
+	assert.Nil(t, err)
+
+	// dropped and broadcast packets are captured as they were sent
+	var dropped, broadcast int
+	for _, r := range records {
+		dropped += r.Dropped
+		if r.Broadcast {
+			broadcast++
+		}
+	}
+	assert.True(t, dropped > 0)
+	assert.Equal(t, 1, broadcast)
+
+	for id := 0; id < 3; id++ {
+		assert.Nil(t, replayCapture(program, filename, id), "computer %d", id)
+	}
+
+	// a packet computer 1 sent differently
+	for i, r := range records {
+		if r.Sent && r.From == 1 {
+			records[i].Y++
+			break
+		}
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := capture.NewWriter(file)
+	for _, r := range records {
+		w.Write(r)
+	}
+	assert.Nil(t, w.Err())
+	file.Close()
+	assert.EqualError(t, replayCapture(program, filename, 1), "packet 0 differs from the capture")
+	assert.Nil(t, replayCapture(program, filename, 2))
+}
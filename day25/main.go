@@ -5,10 +5,10 @@ import (
 	"fmt"
 	tty "github.com/mattn/go-tty"
 	"github.com/mbordner/advent_of_code_2019/day25/game"
-	"github.com/mbordner/advent_of_code_2019/day25/intcode"
+	"github.com/mbordner/advent_of_code_2019/intcode"
+	"io"
 	"log"
 	"os"
-	"strconv"
 	"strings"
 )
 
@@ -33,19 +33,13 @@ Items here:
 */
 
 func main() {
-	inChan := make(chan string, 40)
-	outChan := make(chan string, 1)
-	quitChan := make(chan string, 1)
+	computer := intcode.NewIntCodeComputer(getProgram("program.txt"), nil, nil, nil, false, nil)
 
-	prompt := ""
+	//if err := computer.Load("./game.json"); err != nil {
+	//	log.Fatal(err)
+	//}
 
-	computer := intcode.NewIntCodeComputer(getProgram("program.txt"), inChan, outChan, quitChan, true, &prompt)
-
-	promptChan := computer.GetPromptChannel()
-
-	//computer.Load("./game.json")
-
-	go computer.Execute()
+	ascii := intcode.NewASCII(computer)
 
 	tty, err := tty.Open()
 	if err != nil {
@@ -58,71 +52,55 @@ func main() {
 	var bot *game.Game
 	bot = game.NewGame([]string{"infinite loop","giant electromagnet","molten lava","escape pod","photons"}, "Security Checkpoint")
 
-	inputQueue := make([]byte, 0, 1024)
+	commands := make([]string, 0, 16)
 
-programLoop:
 	for {
-		select {
-		case <-promptChan:
-			var r rune
-			var err error
-			if len(inputQueue) > 0 {
-				r = rune(inputQueue[0])
-				inputQueue = inputQueue[1:]
-			} else {
-				r, err = tty.ReadRune()
-			}
-
-			if err != nil {
-				log.Fatal(err)
-			}
-			fmt.Fprintf(outTTY, "%c", r)
-
-			b := byte(r)
-			if b == 13 {
-				b = 10
-			}
-			inChan <- fmt.Sprintf("%d", b)
-
-		case output := <-outChan:
-
-			v, e := strconv.Atoi(output)
-			if e != nil {
-				panic(e)
-			}
-
-			r := rune(v)
+		text, err := ascii.ReadUntilInput()
 
-			if r == 10 {
-				//computer.Save("./game.json")
-			}
-
-			fmt.Fprintf(outTTY, "%c", r)
+		for _, b := range []byte(text) {
+			fmt.Fprintf(outTTY, "%c", b)
 
 			if bot != nil {
-				bot.OutputByte(byte(r))
-				cmds := bot.GetCurrentCommands()
-				if len(cmds) > 0 {
-					for _, cmd := range cmds {
-						inputQueue = append(inputQueue, []byte(cmd)...)
-						inputQueue = append(inputQueue, byte(10))
-					}
-				}
+				bot.OutputByte(b)
+				commands = append(commands, bot.GetCurrentCommands()...)
 			}
+		}
 
-			computer.OutputProcessed()
-
-		case <-quitChan:
+		if err == io.EOF {
 			fmt.Println("game exited")
-			break programLoop
-
+			break
+		} else if err != intcode.ErrInputNeeded {
+			log.Fatal(err)
 		}
-	}
 
-	close(inChan)
-	close(outChan)
-	close(quitChan)
+		//if err := computer.Save("./game.json"); err != nil {
+		//	log.Println(err)
+		//}
+
+		var command string
+		if len(commands) > 0 {
+			command = commands[0]
+			commands = commands[1:]
+			fmt.Fprintln(outTTY, command)
+		} else {
+			runes := make([]rune, 0, 32)
+			for {
+				r, err := tty.ReadRune()
+				if err != nil {
+					log.Fatal(err)
+				}
+				if r == 13 || r == 10 {
+					fmt.Fprintln(outTTY)
+					break
+				}
+				fmt.Fprintf(outTTY, "%c", r)
+				runes = append(runes, r)
+			}
+			command = string(runes)
+		}
 
+		ascii.WriteLine(command)
+	}
 }
 
 func getProgram(filename string) []string {
@@ -2,114 +2,37 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
+	"github.com/mbordner/advent_of_code_2019/intcode"
+	"log"
 	"os"
 	"strconv"
+	"strings"
 )
 
 const (
 	program1String = `3,225,1,225,6,6,1100,1,238,225,104,0,1102,72,20,224,1001,224,-1440,224,4,224,102,8,223,223,1001,224,5,224,1,224,223,223,1002,147,33,224,101,-3036,224,224,4,224,102,8,223,223,1001,224,5,224,1,224,223,223,1102,32,90,225,101,65,87,224,101,-85,224,224,4,224,1002,223,8,223,101,4,224,224,1,223,224,223,1102,33,92,225,1102,20,52,225,1101,76,89,225,1,117,122,224,101,-78,224,224,4,224,102,8,223,223,101,1,224,224,1,223,224,223,1102,54,22,225,1102,5,24,225,102,50,84,224,101,-4600,224,224,4,224,1002,223,8,223,101,3,224,224,1,223,224,223,1102,92,64,225,1101,42,83,224,101,-125,224,224,4,224,102,8,223,223,101,5,224,224,1,224,223,223,2,58,195,224,1001,224,-6840,224,4,224,102,8,223,223,101,1,224,224,1,223,224,223,1101,76,48,225,1001,92,65,224,1001,224,-154,224,4,224,1002,223,8,223,101,5,224,224,1,223,224,223,4,223,99,0,0,0,677,0,0,0,0,0,0,0,0,0,0,0,1105,0,99999,1105,227,247,1105,1,99999,1005,227,99999,1005,0,256,1105,1,99999,1106,227,99999,1106,0,265,1105,1,99999,1006,0,99999,1006,227,274,1105,1,99999,1105,1,280,1105,1,99999,1,225,225,225,1101,294,0,0,105,1,0,1105,1,99999,1106,0,300,1105,1,99999,1,225,225,225,1101,314,0,0,106,0,0,1105,1,99999,1107,677,226,224,1002,223,2,223,1005,224,329,101,1,223,223,7,677,226,224,102,2,223,223,1005,224,344,1001,223,1,223,1107,226,226,224,1002,223,2,223,1006,224,359,1001,223,1,223,8,226,226,224,1002,223,2,223,1006,224,374,101,1,223,223,108,226,226,224,102,2,223,223,1005,224,389,1001,223,1,223,1008,226,226,224,1002,223,2,223,1005,224,404,101,1,223,223,1107,226,677,224,1002,223,2,223,1006,224,419,101,1,223,223,1008,226,677,224,1002,223,2,223,1006,224,434,101,1,223,223,108,677,677,224,1002,223,2,223,1006,224,449,101,1,223,223,1108,677,226,224,102,2,223,223,1006,224,464,1001,223,1,223,107,677,677,224,102,2,223,223,1005,224,479,101,1,223,223,7,226,677,224,1002,223,2,223,1006,224,494,1001,223,1,223,7,677,677,224,102,2,223,223,1006,224,509,101,1,223,223,107,226,677,224,1002,223,2,223,1006,224,524,1001,223,1,223,1007,226,226,224,102,2,223,223,1006,224,539,1001,223,1,223,108,677,226,224,102,2,223,223,1005,224,554,101,1,223,223,1007,677,677,224,102,2,223,223,1006,224,569,101,1,223,223,8,677,226,224,102,2,223,223,1006,224,584,1001,223,1,223,1008,677,677,224,1002,223,2,223,1006,224,599,1001,223,1,223,1007,677,226,224,1002,223,2,223,1005,224,614,101,1,223,223,1108,226,677,224,1002,223,2,223,1005,224,629,101,1,223,223,1108,677,677,224,1002,223,2,223,1005,224,644,1001,223,1,223,8,226,677,224,1002,223,2,223,1006,224,659,101,1,223,223,107,226,226,224,102,2,223,223,1005,224,674,101,1,223,223,4,223,99,226`
 )
 
-var (
-	opCodes = map[int]int{
-		1:  4,
-		2:  4,
-		3:  2,
-		4:  2,
-		5:  3,
-		6:  3,
-		7:  4,
-		8:  4,
-		99: 0,
-	}
-)
-
-func execute(program []int) []int {
-	i := 0
-instructions:
-	for i < len(program) {
-		if length, ok := opCodes[program[i]%100]; ok {
-			tmp := program[i]
-			opCode := tmp % 100
-			tmp /= 100
-
-			if opCode == 99 {
-				break
-			}
-
-			l := length - 1
-
-			modes := make([]int, l, l)
-			params := make([]int, l, l)
-
-			for j := 1; j < length; j++ {
-				modes[j-1] = tmp % 10
-				params[j-1] = i + j
-				if modes[j-1] == 0 {
-					params[j-1] = program[params[j-1]]
-				}
-				tmp /= 10
-			}
-
-			switch opCode {
-			case 1:
-				program[params[2]] = program[params[0]] + program[params[1]]
-			case 2:
-				program[params[2]] = program[params[0]] * program[params[1]]
-			case 3:
-				reader := bufio.NewReader(os.Stdin)
-				fmt.Print("input value: ")
-				text, _ := reader.ReadString('\n')
-				value, err := strconv.Atoi(text[0 : len(text)-1])
-				if err != nil {
-					panic(err)
-				}
-				program[params[0]] = value
-			case 4:
-				fmt.Println("output: ", program[params[0]])
-			case 5:
-				if program[params[0]] != 0 {
-					i = program[params[1]]
-					continue instructions
-				}
-			case 6:
-				if program[params[0]] == 0 {
-					i = program[params[1]]
-					continue instructions
-				}
-			case 7:
-				if program[params[0]] < program[params[1]] {
-					program[params[2]] = 1
-				} else {
-					program[params[2]] = 0
-				}
-			case 8:
-				if program[params[0]] == program[params[1]] {
-					program[params[2]] = 1
-				} else {
-					program[params[2]] = 0
-				}
-			}
-
-			i += length
-
-		} else {
-			panic(fmt.Errorf("invalid opcode %d at pos %d", program[i], i))
-		}
-	}
-	return program
-}
-
 func main() {
-	var program []int
-	err := json.Unmarshal([]byte(`[`+program1String+`]`), &program)
-	if err != nil {
-		panic(err)
+	reader := bufio.NewReader(os.Stdin)
+	in := intcode.InputFunc(func() (int64, error) {
+		fmt.Print("input value: ")
+		text, err := reader.ReadString('\n')
+		if err != nil {
+			return 0, err
+		}
+		return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	})
+	out := intcode.OutputFunc(func(value int64) error {
+		fmt.Println("output: ", value)
+		return nil
+	})
+
+	c := intcode.NewIntCodeComputerWithDevices(strings.Split(program1String, ","), in, out)
+	if err := c.Execute(); err != nil {
+		log.Fatal(err)
 	}
-
-	execute(program)
 }
 
 /**
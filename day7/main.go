@@ -2,228 +2,32 @@ package main
 
 import (
 	"fmt"
+	"github.com/mbordner/advent_of_code_2019/intcode"
+	"log"
+	"strings"
 )
 
-var (
-	opCodes = map[int]int{
-		1:  4,
-		2:  4,
-		3:  2,
-		4:  2,
-		5:  3,
-		6:  3,
-		7:  4,
-		8:  4,
-		99: 0,
-	}
-)
-
-func execute(program []int, in <-chan int, out chan<- int, quit chan<- int) []int {
-	var lastOut int
-
-	i := 0
-instructions:
-	for i < len(program) {
-		if length, ok := opCodes[program[i]%100]; ok {
-			tmp := program[i]
-			opCode := tmp % 100
-			tmp /= 100
-
-			if opCode == 99 {
-				quit <- lastOut
-				break
-			}
-
-			l := length - 1
-
-			modes := make([]int, l, l)
-			params := make([]int, l, l)
-
-			for j := 1; j < length; j++ {
-				modes[j-1] = tmp % 10
-				params[j-1] = i + j
-				if modes[j-1] == 0 {
-					params[j-1] = program[params[j-1]]
-				}
-				tmp /= 10
-			}
-
-			switch opCode {
-			case 1:
-				program[params[2]] = program[params[0]] + program[params[1]]
-			case 2:
-				program[params[2]] = program[params[0]] * program[params[1]]
-			case 3:
-				/*
-					reader := bufio.NewReader(os.Stdin)
-					text, _ := reader.ReadString('\n')
-					value, err := strconv.Atoi(text[0 : len(text)-1])
-					if err != nil {
-						panic(err)
-					}
-				*/
-				value := <-in
-				program[params[0]] = value
-			case 4:
-				/*
-					fmt.Println(program[params[0]])
-				*/
-				lastOut = program[params[0]]
-				out <- lastOut
-			case 5:
-				if program[params[0]] != 0 {
-					i = program[params[1]]
-					continue instructions
-				}
-			case 6:
-				if program[params[0]] == 0 {
-					i = program[params[1]]
-					continue instructions
-				}
-			case 7:
-				if program[params[0]] < program[params[1]] {
-					program[params[2]] = 1
-				} else {
-					program[params[2]] = 0
-				}
-			case 8:
-				if program[params[0]] == program[params[1]] {
-					program[params[2]] = 1
-				} else {
-					program[params[2]] = 0
-				}
-			}
-
-			i += length
-
-		} else {
-			panic(fmt.Errorf("invalid opcode %d at pos %d", program[i], i))
-		}
-	}
-	return program
-}
-
-func nextPerm(p []int) {
-	for i := len(p) - 1; i >= 0; i-- {
-		if i == 0 || p[i] < len(p)-i-1 {
-			p[i]++
-			return
-		}
-		p[i] = 0
-	}
-}
-
-func getPerm(orig, p []int) []int {
-	result := append([]int{}, orig...)
-	for i, v := range p {
-		result[i], result[i+v] = result[i+v], result[i]
-	}
-	return result
-}
-
-/*
 func main() {
-	program := []int{3,8,1001,8,10,8,105,1,0,0,21,46,55,76,89,106,187,268,349,430,99999,3,9,101,4,9,9,1002,9,2,9,101,5,9,9,1002,9,2,9,101,2,9,9,4,9,99,3,9,1002,9,5,9,4,9,99,3,9,1001,9,2,9,1002,9,4,9,101,2,9,9,1002,9,3,9,4,9,99,3,9,1001,9,3,9,1002,9,2,9,4,9,99,3,9,1002,9,4,9,1001,9,4,9,102,5,9,9,4,9,99,3,9,101,1,9,9,4,9,3,9,102,2,9,9,4,9,3,9,1001,9,2,9,4,9,3,9,101,2,9,9,4,9,3,9,1001,9,1,9,4,9,3,9,101,1,9,9,4,9,3,9,102,2,9,9,4,9,3,9,102,2,9,9,4,9,3,9,1002,9,2,9,4,9,3,9,101,1,9,9,4,9,99,3,9,102,2,9,9,4,9,3,9,1002,9,2,9,4,9,3,9,101,1,9,9,4,9,3,9,101,2,9,9,4,9,3,9,102,2,9,9,4,9,3,9,102,2,9,9,4,9,3,9,102,2,9,9,4,9,3,9,1001,9,1,9,4,9,3,9,101,2,9,9,4,9,3,9,1002,9,2,9,4,9,99,3,9,101,1,9,9,4,9,3,9,101,1,9,9,4,9,3,9,101,2,9,9,4,9,3,9,1002,9,2,9,4,9,3,9,1001,9,2,9,4,9,3,9,1001,9,1,9,4,9,3,9,1001,9,2,9,4,9,3,9,102,2,9,9,4,9,3,9,102,2,9,9,4,9,3,9,102,2,9,9,4,9,99,3,9,101,1,9,9,4,9,3,9,102,2,9,9,4,9,3,9,101,2,9,9,4,9,3,9,101,1,9,9,4,9,3,9,102,2,9,9,4,9,3,9,1002,9,2,9,4,9,3,9,102,2,9,9,4,9,3,9,1001,9,2,9,4,9,3,9,102,2,9,9,4,9,3,9,101,1,9,9,4,9,99,3,9,1001,9,1,9,4,9,3,9,1001,9,1,9,4,9,3,9,102,2,9,9,4,9,3,9,102,2,9,9,4,9,3,9,1001,9,1,9,4,9,3,9,1001,9,1,9,4,9,3,9,1001,9,1,9,4,9,3,9,1002,9,2,9,4,9,3,9,101,2,9,9,4,9,3,9,101,1,9,9,4,9,99}
-
-	var bestPermutation []int
-	var maxThrusterSignal int
-
-	phaseSettingSequenceStart := []int{0,1,2,3,4}
-	for p := make([]int, len(phaseSettingSequenceStart)); p[0] < len(p); nextPerm(p) {
-		permutation := getPerm(phaseSettingSequenceStart, p)
-
-		signal := 0
-
-		for i := 0; i < len(permutation); i++ {
-			in := make(chan int, 1)
-			out := make(chan int, 1)
-
-			freshProgram := make([]int,len(program),len(program))
-			copy(freshProgram,program)
-
-			go execute(program,in,out)
-
-			in <- permutation[i]
-			in <- signal
-
-			signal = <- out
-
-			close(in)
-			close(out)
-		}
-
-		if signal > maxThrusterSignal {
-			maxThrusterSignal = signal
-			bestPermutation = make([]int,len(permutation),len(permutation))
-			copy(bestPermutation,permutation)
-		}
+	program := strings.Split(`3,8,1001,8,10,8,105,1,0,0,21,46,55,76,89,106,187,268,349,430,99999,3,9,101,4,9,9,1002,9,2,9,101,5,9,9,1002,9,2,9,101,2,9,9,4,9,99,3,9,1002,9,5,9,4,9,99,3,9,1001,9,2,9,1002,9,4,9,101,2,9,9,1002,9,3,9,4,9,99,3,9,1001,9,3,9,1002,9,2,9,4,9,99,3,9,1002,9,4,9,1001,9,4,9,102,5,9,9,4,9,99,3,9,101,1,9,9,4,9,3,9,102,2,9,9,4,9,3,9,1001,9,2,9,4,9,3,9,101,2,9,9,4,9,3,9,1001,9,1,9,4,9,3,9,101,1,9,9,4,9,3,9,102,2,9,9,4,9,3,9,102,2,9,9,4,9,3,9,1002,9,2,9,4,9,3,9,101,1,9,9,4,9,99,3,9,102,2,9,9,4,9,3,9,1002,9,2,9,4,9,3,9,101,1,9,9,4,9,3,9,101,2,9,9,4,9,3,9,102,2,9,9,4,9,3,9,102,2,9,9,4,9,3,9,102,2,9,9,4,9,3,9,1001,9,1,9,4,9,3,9,101,2,9,9,4,9,3,9,1002,9,2,9,4,9,99,3,9,101,1,9,9,4,9,3,9,101,1,9,9,4,9,3,9,101,2,9,9,4,9,3,9,1002,9,2,9,4,9,3,9,1001,9,2,9,4,9,3,9,1001,9,1,9,4,9,3,9,1001,9,2,9,4,9,3,9,102,2,9,9,4,9,3,9,102,2,9,9,4,9,3,9,102,2,9,9,4,9,99,3,9,101,1,9,9,4,9,3,9,102,2,9,9,4,9,3,9,101,2,9,9,4,9,3,9,101,1,9,9,4,9,3,9,102,2,9,9,4,9,3,9,1002,9,2,9,4,9,3,9,102,2,9,9,4,9,3,9,1001,9,2,9,4,9,3,9,102,2,9,9,4,9,3,9,101,1,9,9,4,9,99,3,9,1001,9,1,9,4,9,3,9,1001,9,1,9,4,9,3,9,102,2,9,9,4,9,3,9,102,2,9,9,4,9,3,9,1001,9,1,9,4,9,3,9,1001,9,1,9,4,9,3,9,1001,9,1,9,4,9,3,9,1002,9,2,9,4,9,3,9,101,2,9,9,4,9,3,9,101,1,9,9,4,9,99`, ",")
 
+	phases, signal, err := intcode.SearchPhases([]int64{0, 1, 2, 3, 4}, intcode.Maximize, func(phases []int64) (int64, error) {
+		return intcode.Chain(program, phases, 0).Run()
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
-
-
-	fmt.Println("max signal: ", maxThrusterSignal)
-
-}
-*/
-
-type Amplifier struct {
-	in      chan int
-	out     chan int
-	quit    chan int
-	program []int
-}
-
-func NewAmplifier(program []int, signal int, in chan int, out chan int) *Amplifier {
-	a := new(Amplifier)
-	a.in = in
-	a.out = out
-	a.quit = make(chan int, 1)
-	a.program = make([]int, len(program), len(program))
-	copy(a.program, program)
-	go execute(a.program, a.in, a.out, a.quit)
-	a.in <- signal
-	return a
-}
-
-func main() {
-	program := []int{3, 8, 1001, 8, 10, 8, 105, 1, 0, 0, 21, 46, 55, 76, 89, 106, 187, 268, 349, 430, 99999, 3, 9, 101, 4, 9, 9, 1002, 9, 2, 9, 101, 5, 9, 9, 1002, 9, 2, 9, 101, 2, 9, 9, 4, 9, 99, 3, 9, 1002, 9, 5, 9, 4, 9, 99, 3, 9, 1001, 9, 2, 9, 1002, 9, 4, 9, 101, 2, 9, 9, 1002, 9, 3, 9, 4, 9, 99, 3, 9, 1001, 9, 3, 9, 1002, 9, 2, 9, 4, 9, 99, 3, 9, 1002, 9, 4, 9, 1001, 9, 4, 9, 102, 5, 9, 9, 4, 9, 99, 3, 9, 101, 1, 9, 9, 4, 9, 3, 9, 102, 2, 9, 9, 4, 9, 3, 9, 1001, 9, 2, 9, 4, 9, 3, 9, 101, 2, 9, 9, 4, 9, 3, 9, 1001, 9, 1, 9, 4, 9, 3, 9, 101, 1, 9, 9, 4, 9, 3, 9, 102, 2, 9, 9, 4, 9, 3, 9, 102, 2, 9, 9, 4, 9, 3, 9, 1002, 9, 2, 9, 4, 9, 3, 9, 101, 1, 9, 9, 4, 9, 99, 3, 9, 102, 2, 9, 9, 4, 9, 3, 9, 1002, 9, 2, 9, 4, 9, 3, 9, 101, 1, 9, 9, 4, 9, 3, 9, 101, 2, 9, 9, 4, 9, 3, 9, 102, 2, 9, 9, 4, 9, 3, 9, 102, 2, 9, 9, 4, 9, 3, 9, 102, 2, 9, 9, 4, 9, 3, 9, 1001, 9, 1, 9, 4, 9, 3, 9, 101, 2, 9, 9, 4, 9, 3, 9, 1002, 9, 2, 9, 4, 9, 99, 3, 9, 101, 1, 9, 9, 4, 9, 3, 9, 101, 1, 9, 9, 4, 9, 3, 9, 101, 2, 9, 9, 4, 9, 3, 9, 1002, 9, 2, 9, 4, 9, 3, 9, 1001, 9, 2, 9, 4, 9, 3, 9, 1001, 9, 1, 9, 4, 9, 3, 9, 1001, 9, 2, 9, 4, 9, 3, 9, 102, 2, 9, 9, 4, 9, 3, 9, 102, 2, 9, 9, 4, 9, 3, 9, 102, 2, 9, 9, 4, 9, 99, 3, 9, 101, 1, 9, 9, 4, 9, 3, 9, 102, 2, 9, 9, 4, 9, 3, 9, 101, 2, 9, 9, 4, 9, 3, 9, 101, 1, 9, 9, 4, 9, 3, 9, 102, 2, 9, 9, 4, 9, 3, 9, 1002, 9, 2, 9, 4, 9, 3, 9, 102, 2, 9, 9, 4, 9, 3, 9, 1001, 9, 2, 9, 4, 9, 3, 9, 102, 2, 9, 9, 4, 9, 3, 9, 101, 1, 9, 9, 4, 9, 99, 3, 9, 1001, 9, 1, 9, 4, 9, 3, 9, 1001, 9, 1, 9, 4, 9, 3, 9, 102, 2, 9, 9, 4, 9, 3, 9, 102, 2, 9, 9, 4, 9, 3, 9, 1001, 9, 1, 9, 4, 9, 3, 9, 1001, 9, 1, 9, 4, 9, 3, 9, 1001, 9, 1, 9, 4, 9, 3, 9, 1002, 9, 2, 9, 4, 9, 3, 9, 101, 2, 9, 9, 4, 9, 3, 9, 101, 1, 9, 9, 4, 9, 99}
-	phaseSettingSequenceStart := []int{5, 6, 7, 8, 9}
-
-	var bestPermutation []int
-	var maxThrusterSignal int
-
-	for p := make([]int, len(phaseSettingSequenceStart)); p[0] < len(p); nextPerm(p) {
-		permutation := getPerm(phaseSettingSequenceStart, p)
-
-		amplifiers := make([]*Amplifier, len(phaseSettingSequenceStart), len(phaseSettingSequenceStart))
-
-		in := make(chan int, 1)
-
-		amplifiers[0] = NewAmplifier(program, permutation[0], in, make(chan int, 1))
-		amplifiers[1] = NewAmplifier(program, permutation[1], amplifiers[0].out, make(chan int, 1))
-		amplifiers[2] = NewAmplifier(program, permutation[2], amplifiers[1].out, make(chan int, 1))
-		amplifiers[3] = NewAmplifier(program, permutation[3], amplifiers[2].out, make(chan int, 1))
-		amplifiers[4] = NewAmplifier(program, permutation[4], amplifiers[3].out, in)
-
-		signal := 0
-
-		in <- signal
-
-		signal = <-amplifiers[4].quit
-
-		if signal > maxThrusterSignal {
-			maxThrusterSignal = signal
-			bestPermutation = make([]int, len(permutation), len(permutation))
-			copy(bestPermutation, permutation)
-		}
-
+	fmt.Println("max signal: ", signal, phases)
+
+	// in the feedback loop the last amplifier's last output goes to the thrusters
+	phases, signal, err = intcode.SearchPhases([]int64{5, 6, 7, 8, 9}, intcode.Maximize, func(phases []int64) (int64, error) {
+		return intcode.Loop(program, phases, 0).Run()
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
-
-	fmt.Println("max signal: ", maxThrusterSignal)
-
+	fmt.Println("max feedback loop signal: ", signal, phases)
 }
 
-
 /**
 
 --- Day 7: Amplification Circuit ---
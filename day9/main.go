@@ -3,231 +3,34 @@ package main
 import (
 	"bufio"
 	"fmt"
-	"math/big"
+	"github.com/mbordner/advent_of_code_2019/intcode"
+	"log"
 	"os"
 	"strconv"
 	"strings"
 )
 
-var (
-	// op code to number of bytes including op code and parameters
-	opCodes = map[int]int{
-		1:  4, // add first 2 params, set 3 param location to value
-		2:  4, // multiply first 2 params, set 3 param location to value
-		3:  2, // read string
-		4:  2, // output string
-		5:  3, // if param 0 is not 0, jump to param 1 location
-		6:  3, // if param 0 is 0, jump to param 1 location
-		7:  4, // if param 0 is less than param 1, set location at param 3 to 1, else set it to 0
-		8:  4, // if param 0 is equal to param 1, set location at param 3 to 1, else set it to 0
-		9:  2, // set relative base
-		99: 0, // halt
-	}
-)
-
-func getProgramValue(program []string, pos int) string {
-	if pos < len(program) {
-		return program[pos]
-	}
-	return "0"
-}
-
-func setProgramValue(program []string, pos int, val string) []string {
-	if pos < len(program) {
-		program[pos] = val
-		return program
-	}
-	newProgram := make([]string, pos+1, pos+1)
-	copy(newProgram, program)
-	newProgram[pos] = val
-	return newProgram
-}
-
-func execute(program []string, in chan string, out chan<- string, quit chan<- string) []string {
-
-	var lastOut string
-
-	var relativeBase int
-
-	i := 0
-instructions:
-	for i < len(program) {
-		opCode, err := strconv.Atoi(program[i])
-		if err != nil {
-			panic(err)
-		}
-		if length, ok := opCodes[opCode%100]; ok {
-			tmp := opCode
-			opCode = tmp % 100
-			tmp /= 100
-
-			if opCode == 99 {
-				quit <- lastOut
-				break
-			}
-
-			l := length - 1
-
-			// parameter address modes
-			modes := make([]int, l, l)
-			// op code paramPositions
-			paramPositions := make([]int, l, l)
-
-			for j := 1; j < length; j++ {
-				modes[j-1] = tmp % 10
-
-				switch modes[j-1] {
-				case 0:
-					// position mode
-					pos, err := strconv.Atoi(program[i+j])
-					if err != nil {
-						panic(err)
-					}
-					paramPositions[j-1] = pos
-				default:
-					fallthrough
-				case 1:
-					// immediate mode
-					paramPositions[j-1] = i + j
-				case 2:
-					// relative mode
-					pos, err := strconv.Atoi(program[i+j])
-					if err != nil {
-						panic(err)
-					}
-					paramPositions[j-1] = pos + relativeBase
-				}
-
-				tmp /= 10
-			}
-
-			switch opCode {
-			case 1:
-				val1, ok := new(big.Int).SetString(getProgramValue(program,paramPositions[0]), 10)
-				if !ok {
-					panic(fmt.Errorf("invalid int %s", getProgramValue(program,paramPositions[0])))
-				}
-				val2, ok := new(big.Int).SetString(getProgramValue(program,paramPositions[1]), 10)
-				if !ok {
-					panic(fmt.Errorf("invalid int %s", getProgramValue(program,paramPositions[1])))
-				}
-				val3 := new(big.Int)
-				val3.Add(val1, val2)
-				program = setProgramValue(program, paramPositions[2], val3.Text(10))
-			case 2:
-				val1, ok := new(big.Int).SetString(getProgramValue(program,paramPositions[0]), 10)
-				if !ok {
-					panic(fmt.Errorf("invalid int %s", getProgramValue(program,paramPositions[0])))
-				}
-				val2, ok := new(big.Int).SetString(getProgramValue(program,paramPositions[1]), 10)
-				if !ok {
-					panic(fmt.Errorf("invalid int %s", getProgramValue(program,paramPositions[1])))
-				}
-				val3 := new(big.Int)
-				val3.Mul(val1, val2)
-				program = setProgramValue(program, paramPositions[2], val3.Text(10))
-			case 3:
-				in <- "input:"
-				value := <-in
-				program = setProgramValue(program, paramPositions[0], value)
-			case 4:
-				lastOut = getProgramValue(program,paramPositions[0])
-				out <- lastOut
-			case 5:
-				val1, ok := new(big.Int).SetString(getProgramValue(program,paramPositions[0]), 10)
-				if !ok {
-					panic(fmt.Errorf("invalid int %s", getProgramValue(program,paramPositions[0])))
-				}
-				if val1.Int64() != int64(0) {
-					pos, err := strconv.Atoi(getProgramValue(program,paramPositions[1]))
-					if err != nil {
-						panic(err)
-					}
-					i = pos
-					continue instructions
-				}
-			case 6:
-				val1, ok := new(big.Int).SetString(getProgramValue(program,paramPositions[0]), 10)
-				if !ok {
-					panic(fmt.Errorf("invalid int %s", getProgramValue(program,paramPositions[0])))
-				}
-				if val1.Int64() == 0 {
-					pos, err := strconv.Atoi(getProgramValue(program,paramPositions[1]))
-					if err != nil {
-						panic(err)
-					}
-					i = pos
-					continue instructions
-				}
-			case 7:
-				val1, ok := new(big.Int).SetString(getProgramValue(program,paramPositions[0]), 10)
-				if !ok {
-					panic(fmt.Errorf("invalid int %s", getProgramValue(program,paramPositions[0])))
-				}
-				val2, ok := new(big.Int).SetString(getProgramValue(program,paramPositions[1]), 10)
-				if !ok {
-					panic(fmt.Errorf("invalid int %s", getProgramValue(program,paramPositions[1])))
-				}
-				if val1.Cmp(val2) < 0 {
-					program = setProgramValue(program, paramPositions[2], "1")
-				} else {
-					program = setProgramValue(program, paramPositions[2], "0")
-				}
-			case 8:
-				val1, ok := new(big.Int).SetString(getProgramValue(program,paramPositions[0]), 10)
-				if !ok {
-					panic(fmt.Errorf("invalid int %s", getProgramValue(program,paramPositions[0])))
-				}
-				val2, ok := new(big.Int).SetString(getProgramValue(program,paramPositions[1]), 10)
-				if !ok {
-					panic(fmt.Errorf("invalid int %s", getProgramValue(program,paramPositions[1])))
-				}
-				if val1.Cmp(val2) == 0 {
-					program = setProgramValue(program, paramPositions[2], "1")
-				} else {
-					program = setProgramValue(program, paramPositions[2], "0")
-				}
-			case 9:
-				val, err := strconv.Atoi(getProgramValue(program,paramPositions[0]))
-				if err != nil {
-					panic(err)
-				}
-				relativeBase += val
-			}
-
-			i += length
-
-		} else {
-			panic(fmt.Errorf("invalid opcode %d at pos %d", program[i], i))
-		}
-	}
-	return program
-}
-
 func main() {
-
-	in := make(chan string, 1)
-	out := make(chan string, 1)
-	quit := make(chan string, 1)
-
-	go execute(getPart1Program(), in, out, quit)
-
-programLoop:
-	for {
-		select {
-		case prompt := <-in:
-			reader := bufio.NewReader(os.Stdin)
-			fmt.Print(prompt)
-			text, _ := reader.ReadString('\n')
-			in <- text[0 : len(text)-1]
-		case val := <-out:
-			fmt.Println(val)
-		case lastOut := <-quit:
-			fmt.Println("program exited with last output: ", lastOut)
-			break programLoop
+	reader := bufio.NewReader(os.Stdin)
+	in := intcode.InputFunc(func() (int64, error) {
+		fmt.Print("input:")
+		text, err := reader.ReadString('\n')
+		if err != nil {
+			return 0, err
 		}
+		return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	})
+	out := intcode.OutputFunc(func(value int64) error {
+		fmt.Println(value)
+		return nil
+	})
+
+	c := intcode.NewIntCodeComputerWithDevices(getPart1Program(), in, out)
+	err := c.Execute()
+	if err != nil {
+		log.Fatal(err)
 	}
-
+	fmt.Println("program exited with last output: ", c.Registers().LastOut)
 }
 
 func getProgramTest1() []string {
@@ -0,0 +1,139 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrInputNeeded is returned by ASCII reads when the program is waiting for input.
+var ErrInputNeeded = errors.New("program needs input")
+
+// NonASCIIOutput is returned by ASCII reads when the program outputs a value that isn't ASCII, such as
+// the answers the ASCII programs print at the end.
+type NonASCIIOutput struct {
+	Value int64
+	Text  string // the value in the string program format, which may need arbitrary precision
+}
+
+func (e *NonASCIIOutput) Error() string {
+	return fmt.Sprintf("non ASCII output %s", e.Text)
+}
+
+// ASCII runs an ASCII capable program, turning its output into text and text into its input.  It
+// drives the computer with the step API, so the computer must not be executing.
+//
+// Reads run the program until they have enough output.  They return ErrInputNeeded when the program
+// waits for input, a *NonASCIIOutput when it outputs a value above 127, io.EOF once it has halted and
+// all of its output has been read, or the program's fault.  Text output before any of these is
+// returned with them.
+type ASCII struct {
+	c       *IntCodeComputer
+	buf     []byte
+	pending error
+}
+
+func NewASCII(c *IntCodeComputer) *ASCII {
+	return &ASCII{c: c}
+}
+
+func (a *ASCII) Computer() *IntCodeComputer {
+	return a.c
+}
+
+// Write queues p as input.
+func (a *ASCII) Write(p []byte) (int, error) {
+	for _, b := range p {
+		a.c.ProvideInput(int64(b))
+	}
+	return len(p), nil
+}
+
+// WriteLine queues line followed by a newline as input.
+func (a *ASCII) WriteLine(line string) {
+	a.Write([]byte(line))
+	a.c.ProvideInput('\n')
+}
+
+// fill runs the program until it outputs another character.  Anything else that stops it is kept as
+// the pending error, so text already buffered is read first.
+func (a *ASCII) fill() bool {
+	if a.pending != nil {
+		return false
+	}
+	status, err := a.c.RunUntilOutput()
+	switch status {
+	case ProducedOutput:
+		// Output is 0 for values that need arbitrary precision, so the text is what is checked
+		v, text := a.c.Output(), a.c.OutputText()
+		if n, err := strconv.ParseInt(text, 10, 64); err != nil || n < 0 || n > 127 {
+			a.pending = &NonASCIIOutput{Value: v, Text: text}
+			return false
+		}
+		a.buf = append(a.buf, byte(v))
+		return true
+	case NeedsInput:
+		a.pending = ErrInputNeeded
+	case Halted:
+		a.pending = io.EOF
+	default:
+		if err == nil {
+			err = fmt.Errorf("program %s", status)
+		}
+		a.pending = err
+	}
+	return false
+}
+
+// takePending returns the error that stopped the last read and clears it, so the next read runs the
+// program again.
+func (a *ASCII) takePending() error {
+	err := a.pending
+	if err != io.EOF {
+		a.pending = nil
+	}
+	return err
+}
+
+// Read reads ASCII output.
+func (a *ASCII) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for len(a.buf) < len(p) && a.fill() {
+	}
+	n := copy(p, a.buf)
+	a.buf = a.buf[n:]
+	if n == len(p) {
+		return n, nil
+	}
+	return n, a.takePending()
+}
+
+// ReadLine reads a line of output without its newline.
+func (a *ASCII) ReadLine() (string, error) {
+	for {
+		if i := strings.IndexByte(string(a.buf), '\n'); i >= 0 {
+			line := string(a.buf[:i])
+			a.buf = a.buf[i+1:]
+			return line, nil
+		}
+		if !a.fill() {
+			line := string(a.buf)
+			a.buf = nil
+			return line, a.takePending()
+		}
+	}
+}
+
+// ReadUntilInput reads all output up to the next input instruction, which it reports with
+// ErrInputNeeded, or up to whatever else stops the program.
+func (a *ASCII) ReadUntilInput() (string, error) {
+	for a.fill() {
+	}
+	text := string(a.buf)
+	a.buf = nil
+	return text, a.takePending()
+}
@@ -0,0 +1,101 @@
+package intcode
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// asks for a name, greets it, and outputs 1000 plus how long it was
+const asciiGreeter = `
+MACRO print addr, n
+        ADD  #addr, #0, [.ptr@+1]
+        ADD  #n, #0, [len]
+.ptr@:  OUT  [0]
+        ADD  [.ptr@+1], #1, [.ptr@+1]
+        ADD  [len], #-1, [len]
+        JNZ  [len], #.ptr@
+ENDM
+main:   print ask, 6
+        ADD  #0, #0, [len]
+        print hi, 3
+.read:  IN   [c]
+        EQ   [c], #10, [t]
+        JNZ  [t], #.done
+        OUT  [c]
+        ADD  [count], #1, [count]
+        JZ   #0, #.read
+.done:  OUT  #10
+        ADD  [count], #1000, [count]
+        OUT  [count]
+        OUT  #1125899906842624
+        HLT
+ask:    DATA "Name?\n"
+hi:     DATA "Hi "
+len:    DATA 0
+c:      DATA 0
+t:      DATA 0
+count:  DATA 0
+`
+
+func Test_ASCII(t *testing.T) {
+	assembled, err := Assemble(asciiGreeter)
+	assert.Nil(t, err)
+	a := NewASCII(NewIntCodeComputer(assembled.Program, nil, nil, nil, false, nil))
+
+	line, err := a.ReadLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "Name?", line)
+
+	text, err := a.ReadUntilInput()
+	assert.Equal(t, ErrInputNeeded, err)
+	assert.Equal(t, "Hi ", text)
+
+	line, err = a.ReadLine()
+	assert.Equal(t, ErrInputNeeded, err)
+	assert.Equal(t, "", line)
+
+	a.WriteLine("Bob")
+	line, err = a.ReadLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "Bob", line)
+
+	line, err = a.ReadLine()
+	assert.Equal(t, "", line)
+	assert.Equal(t, &NonASCIIOutput{Value: 1003, Text: "1003"}, err)
+
+	_, err = a.ReadLine()
+	assert.Equal(t, &NonASCIIOutput{Value: 1125899906842624, Text: "1125899906842624"}, err)
+
+	_, err = a.ReadLine()
+	assert.Equal(t, io.EOF, err)
+	_, err = a.ReadLine()
+	assert.Equal(t, io.EOF, err)
+}
+
+func Test_ASCIIReader(t *testing.T) {
+	assembled, err := Assemble(asciiGreeter)
+	assert.Nil(t, err)
+	a := NewASCII(NewIntCodeComputer(assembled.Program, nil, nil, nil, false, nil))
+
+	io.WriteString(a, "Alice\n")
+	b, err := ioutil.ReadAll(a)
+	assert.Equal(t, "Name?\nHi Alice\n", string(b))
+	assert.Equal(t, &NonASCIIOutput{Value: 1005, Text: "1005"}, err)
+
+	buf := make([]byte, 4)
+	n, err := a.Read(buf)
+	assert.Equal(t, 0, n)
+	assert.IsType(t, &NonASCIIOutput{}, err)
+	n, err = a.Read(buf)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, "non ASCII output 1005", (&NonASCIIOutput{Value: 1005, Text: "1005"}).Error())
+
+	// values that need arbitrary precision aren't ASCII, although Output reads them as 0
+	a = NewASCII(NewIntCodeComputer(strings.Split(`104,100000000000000000000,99`, ","), nil, nil, nil, false, nil))
+	_, err = a.ReadLine()
+	assert.Equal(t, &NonASCIIOutput{Value: 0, Text: "100000000000000000000"}, err)
+}
@@ -0,0 +1,472 @@
+package intcode
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Assembled is an assembled program and the addresses of its labels.  Labels local to a global label
+// are named global.local.
+type Assembled struct {
+	Program []string
+	Symbols map[string]int
+}
+
+// SymbolAt returns the label for addr, if there is one.  Global labels are preferred.
+func (a *Assembled) SymbolAt(addr int) (string, bool) {
+	return symbolAt(a.Symbols, addr)
+}
+
+func symbolAt(symbols map[string]int, addr int) (string, bool) {
+	var names []string
+	for name, a := range symbols {
+		if a == addr {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	sort.Slice(names, func(i, j int) bool {
+		li, lj := strings.Count(names[i], "."), strings.Count(names[j], ".")
+		if li != lj {
+			return li < lj
+		}
+		return names[i] < names[j]
+	})
+	return names[0], true
+}
+
+var (
+	labelRegex = regexp.MustCompile(`^\s*(\.?[A-Za-z_]\w*):`)
+	nameRegex  = regexp.MustCompile(`^\.?[A-Za-z_][\w]*$`)
+	termRegex  = regexp.MustCompile(`^([+-]?)\s*([^\s+-]+)\s*`)
+)
+
+var opCodesByMnemonic = func() map[string]int {
+	m := make(map[string]int)
+	for op, name := range mnemonics {
+		m[name] = op
+	}
+	return m
+}()
+
+// Assemble translates the mnemonic text format used by Disassemble into a program.
+//
+// Each line holds an optional label, then an instruction, a directive or a macro call.  A semicolon
+// starts a comment.
+//
+//	loop:   ADD  [count], #-1, [count]   ; position, immediate and position parameters
+//	        JNZ  [count], #loop
+//	        OUT  rb-1                    ; relative parameter
+//	        HLT
+//	count:  DATA 10, "text\n"            ; ints and Go quoted strings
+//
+// Labels starting with a dot are local to the last global label.  Parameter values are sums and
+// differences of numbers and labels.
+//
+//	LOCAL name offset
+//
+// names a relative base offset until the next global label, so rb+name can be used for a function's
+// locals.
+//
+//	MACRO name a, b
+//	        ADD  a, b, rb+0
+//	ENDM
+//
+// defines a macro.  Calling it substitutes the arguments for the parameter names, and any @ in the
+// body is replaced with a number unique to the call, so a macro can define its own local labels such
+// as .ret@.
+func Assemble(source string) (*Assembled, error) {
+	lines, err := expandMacros(strings.Split(source, "\n"))
+	if err != nil {
+		return nil, err
+	}
+
+	a := &assembler{symbols: make(map[string]int)}
+
+	// first pass finds the address of every label
+	addr := 0
+	for _, l := range lines {
+		s, err := a.parse(l)
+		if err != nil {
+			return nil, err
+		}
+		if s.label != "" {
+			if _, ok := a.symbols[s.label]; ok {
+				return nil, l.errorf("duplicate label %s", s.label)
+			}
+			a.symbols[s.label] = addr
+		}
+		n, err := a.size(l, s)
+		if err != nil {
+			return nil, err
+		}
+		addr += n
+	}
+
+	// second pass emits the program
+	a.global = ""
+	for _, l := range lines {
+		s, _ := a.parse(l)
+		if err := a.emit(l, s); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Assembled{Program: a.program, Symbols: a.symbols}, nil
+}
+
+type sourceLine struct {
+	number int
+	text   string
+}
+
+func (l sourceLine) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", l.number, fmt.Sprintf(format, args...))
+}
+
+type statement struct {
+	label string
+	op    string
+	args  []string
+}
+
+type assembler struct {
+	symbols map[string]int
+	global  string
+	locals  map[string]int
+	program []string
+}
+
+// stripComment removes a trailing comment, ignoring semicolons in strings.
+func stripComment(text string) string {
+	quoted := false
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return text[:i]
+			}
+		}
+	}
+	return text
+}
+
+// splitArgs splits text on commas, ignoring commas in strings.
+func splitArgs(text string) []string {
+	var args []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				args = append(args, strings.TrimSpace(text[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if rest := strings.TrimSpace(text[start:]); rest != "" || len(args) > 0 {
+		args = append(args, rest)
+	}
+	return args
+}
+
+func splitStatement(text string) (label, op, rest string) {
+	text = stripComment(text)
+	if m := labelRegex.FindStringSubmatch(text); m != nil {
+		label = m[1]
+		text = text[len(m[0]):]
+	}
+	text = strings.TrimSpace(text)
+	if i := strings.IndexAny(text, " \t"); i >= 0 {
+		return label, text[:i], strings.TrimSpace(text[i:])
+	}
+	return label, text, ""
+}
+
+func expandMacros(text []string) ([]sourceLine, error) {
+	type macro struct {
+		params []string
+		body   []sourceLine
+	}
+	macros := make(map[string]*macro)
+	var lines []sourceLine
+	var current *macro
+	calls := 0
+
+	var expand func(l sourceLine, depth int) error
+	expand = func(l sourceLine, depth int) error {
+		label, op, rest := splitStatement(l.text)
+		m, ok := macros[op]
+		if !ok {
+			lines = append(lines, l)
+			return nil
+		}
+		if depth > 100 {
+			return l.errorf("macro %s expands recursively", op)
+		}
+		args := splitArgs(rest)
+		if len(args) != len(m.params) {
+			return l.errorf("macro %s takes %d arguments, got %d", op, len(m.params), len(args))
+		}
+		if label != "" {
+			lines = append(lines, sourceLine{number: l.number, text: label + ":"})
+		}
+		calls++
+		unique := "_" + strconv.Itoa(calls)
+		for _, b := range m.body {
+			body := strings.Replace(stripComment(b.text), "@", unique, -1)
+			for i, p := range m.params {
+				body = regexp.MustCompile(`\b`+regexp.QuoteMeta(p)+`\b`).ReplaceAllLiteralString(body, args[i])
+			}
+			if err := expand(sourceLine{number: l.number, text: body}, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i, t := range text {
+		l := sourceLine{number: i + 1, text: t}
+		label, op, rest := splitStatement(t)
+		switch strings.ToUpper(op) {
+		case "MACRO":
+			if current != nil {
+				return nil, l.errorf("nested MACRO")
+			}
+			fields := strings.Fields(strings.Replace(rest, ",", " ", -1))
+			if len(fields) == 0 || label != "" {
+				return nil, l.errorf("MACRO needs a name")
+			}
+			current = &macro{params: fields[1:]}
+			macros[fields[0]] = current
+		case "ENDM":
+			if current == nil {
+				return nil, l.errorf("ENDM without MACRO")
+			}
+			current = nil
+		default:
+			if current != nil {
+				current.body = append(current.body, l)
+			} else if err := expand(l, 0); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("MACRO without ENDM")
+	}
+	return lines, nil
+}
+
+func (a *assembler) parse(l sourceLine) (statement, error) {
+	label, op, rest := splitStatement(l.text)
+	s := statement{op: strings.ToUpper(op), args: splitArgs(rest)}
+	if label != "" {
+		if strings.HasPrefix(label, ".") {
+			if a.global == "" {
+				return s, l.errorf("local label %s before any global label", label)
+			}
+			label = a.global + label
+		} else {
+			a.global = label
+			a.locals = nil
+		}
+		s.label = label
+	}
+	return s, nil
+}
+
+func (a *assembler) size(l sourceLine, s statement) (int, error) {
+	switch s.op {
+	case "":
+		return 0, nil
+	case "LOCAL":
+		return 0, nil
+	case "DATA":
+		n := 0
+		for _, arg := range s.args {
+			if strings.HasPrefix(arg, `"`) {
+				text, err := strconv.Unquote(arg)
+				if err != nil {
+					return 0, l.errorf("invalid string %s", arg)
+				}
+				n += len([]rune(text))
+			} else {
+				n++
+			}
+		}
+		return n, nil
+	}
+	op, ok := opCodesByMnemonic[s.op]
+	if !ok {
+		return 0, l.errorf("unknown instruction %s", s.op)
+	}
+	length := opCodeLengths[op]
+	if length == 0 {
+		length = 1
+	}
+	if len(s.args) != length-1 {
+		return 0, l.errorf("%s takes %d parameters, got %d", s.op, length-1, len(s.args))
+	}
+	return length, nil
+}
+
+func (a *assembler) emit(l sourceLine, s statement) error {
+	switch s.op {
+	case "":
+		return nil
+	case "LOCAL":
+		fields := strings.Fields(strings.Replace(strings.Join(s.args, " "), ",", " ", -1))
+		if len(fields) != 2 || !nameRegex.MatchString(fields[0]) {
+			return l.errorf("LOCAL needs a name and an offset")
+		}
+		offset, err := a.eval(l, fields[1])
+		if err != nil {
+			return err
+		}
+		if a.locals == nil {
+			a.locals = make(map[string]int)
+		}
+		a.locals[fields[0]] = offset
+		return nil
+	case "DATA":
+		for _, arg := range s.args {
+			if strings.HasPrefix(arg, `"`) {
+				text, _ := strconv.Unquote(arg)
+				for _, r := range text {
+					a.program = append(a.program, strconv.Itoa(int(r)))
+				}
+				continue
+			}
+			if _, _, err := parseCell(arg); err == nil {
+				a.program = append(a.program, arg)
+				continue
+			}
+			v, err := a.eval(l, arg)
+			if err != nil {
+				return err
+			}
+			a.program = append(a.program, strconv.Itoa(v))
+		}
+		return nil
+	}
+
+	op := opCodesByMnemonic[s.op]
+	instruction := op
+	scale := 100
+	var params []string
+	for i, arg := range s.args {
+		mode, value, err := a.param(l, arg)
+		if err != nil {
+			return err
+		}
+		if w, ok := opCodeWriteParams[op]; ok && w == i && mode == 1 {
+			return l.errorf("parameter %d of %s is written to and can't be immediate", i+1, s.op)
+		}
+		instruction += mode * scale
+		scale *= 10
+		params = append(params, strconv.Itoa(value))
+	}
+	a.program = append(a.program, strconv.Itoa(instruction))
+	a.program = append(a.program, params...)
+	return nil
+}
+
+func (a *assembler) param(l sourceLine, arg string) (mode int, value int, err error) {
+	switch {
+	case strings.HasPrefix(arg, "#"):
+		value, err = a.eval(l, arg[1:])
+		return 1, value, err
+	case strings.HasPrefix(arg, "[") && strings.HasSuffix(arg, "]"):
+		value, err = a.eval(l, arg[1:len(arg)-1])
+		return 0, value, err
+	case arg == "rb":
+		return 2, 0, nil
+	case strings.HasPrefix(arg, "rb+") || strings.HasPrefix(arg, "rb-"):
+		value, err = a.eval(l, arg[2:])
+		return 2, value, err
+	}
+	return 0, 0, l.errorf("invalid parameter %s, expected [addr], #value or rb+offset", arg)
+}
+
+// eval evaluates a sum of numbers, labels and locals.
+func (a *assembler) eval(l sourceLine, expr string) (int, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return 0, l.errorf("missing value")
+	}
+	total := 0
+	for first := true; expr != ""; first = false {
+		m := termRegex.FindStringSubmatch(expr)
+		if m == nil {
+			return 0, l.errorf("invalid expression %s", expr)
+		}
+		if m[1] == "" && !first {
+			return 0, l.errorf("missing + or - before %s", expr)
+		}
+		expr = expr[len(m[0]):]
+		v, err := a.term(l, m[2])
+		if err != nil {
+			return 0, err
+		}
+		if m[1] == "-" {
+			total -= v
+		} else {
+			total += v
+		}
+	}
+	return total, nil
+}
+
+func (a *assembler) term(l sourceLine, t string) (int, error) {
+	if v, err := strconv.Atoi(t); err == nil {
+		return v, nil
+	}
+	if v, ok := a.locals[t]; ok {
+		return v, nil
+	}
+	name := t
+	if strings.HasPrefix(t, ".") {
+		name = a.global + t
+	}
+	if v, ok := a.symbols[name]; ok {
+		return v, nil
+	}
+	return 0, l.errorf("undefined symbol %s", t)
+}
+
+// ReadProgramFile reads a program from a file of comma separated values.  Files ending in .asm are
+// assembled first, and the addresses of their labels are returned as well.
+func ReadProgramFile(filename string) ([]string, map[string]int, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	if strings.HasSuffix(filename, ".asm") {
+		assembled, err := Assemble(string(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		return assembled.Program, assembled.Symbols, nil
+	}
+	return strings.Split(strings.TrimSpace(string(data)), ","), nil, nil
+}
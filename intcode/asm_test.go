@@ -0,0 +1,139 @@
+package intcode
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const asmCountdown = `
+; prints "go" after counting down from the input
+MACRO call target
+        ADD  #.ret@, #0, rb+0
+        JNZ  #1, #target
+.ret@:
+ENDM
+
+MACRO print addr, n
+        ADD  #addr, #0, rb+1
+        ADD  #n, #0, rb+2
+        call print
+ENDM
+
+main:   ARB  #stack
+        IN   [count]
+.loop:  OUT  [count]
+        ADD  [count], #-1, [count]
+        JNZ  [count], #.loop
+        print msg, 2
+        HLT
+
+; prints n characters starting at addr
+print:  ARB  #3
+        LOCAL addr -2
+        LOCAL n -1
+.next:  JZ   rb+n, #.done
+        ADD  rb+addr, #0, [.out+1]
+.out:   OUT  [0]
+        ADD  rb+addr, #1, rb+addr
+        ADD  rb+n, #-1, rb+n
+        JNZ  #1, #.next
+.done:  ARB  #-3
+        JNZ  #1, rb+0
+
+count:  DATA 0
+msg:    DATA "go", 1125899906842624 ; text; and a big value
+stack:
+`
+
+func Test_Assemble(t *testing.T) {
+	assembled, err := Assemble(asmCountdown)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, assembled.Symbols["main"])
+	assert.Equal(t, 4, assembled.Symbols["main.loop"])
+	name, ok := assembled.SymbolAt(4)
+	assert.True(t, ok)
+	assert.Equal(t, "main.loop", name)
+
+	msg := assembled.Symbols["msg"]
+	assert.Equal(t, []string{"103", "111", "1125899906842624"}, assembled.Program[msg:])
+
+	c := NewIntCodeComputer(assembled.Program, nil, nil, nil, false, nil)
+	c.ProvideInput(3)
+	status, err := c.RunUntilInputNeeded()
+	assert.Nil(t, err)
+	assert.Equal(t, Halted, status)
+	assert.Equal(t, []int64{3, 2, 1, 'g', 'o'}, c.TakeOutputs())
+
+	listing, err := DisassembleWithSymbols(assembled.Program, assembled.Symbols)
+	assert.Nil(t, err)
+	assert.Contains(t, listing.String(), "main.loop:\n     4: OUT  [")
+	assert.Contains(t, listing.String(), "print:\n")
+	assert.Contains(t, listing.String(), "msg:\n    57: DATA 103, 111, 1125899906842624")
+}
+
+func Test_AssembleErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{"ADD #1, #2, #3", "line 1: parameter 3 of ADD is written to and can't be immediate"},
+		{"\nFOO [1]", "line 2: unknown instruction FOO"},
+		{"OUT [1], [2]", "line 1: OUT takes 1 parameters, got 2"},
+		{"OUT [missing]", "line 1: undefined symbol missing"},
+		{"OUT #1 2", "line 1: missing + or - before 2"},
+		{"a: OUT [a 1]", "line 1: missing + or - before 1"},
+		{"OUT 1", "line 1: invalid parameter 1, expected [addr], #value or rb+offset"},
+		{"a: HLT\na: HLT", "line 2: duplicate label a"},
+		{".a: HLT", "line 1: local label .a before any global label"},
+		{"MACRO m x\nOUT x", "MACRO without ENDM"},
+		{"MACRO m x\nOUT x\nENDM\nm #1, #2", "line 4: macro m takes 1 arguments, got 2"},
+	}
+
+	for _, test := range tests {
+		_, err := Assemble(test.source)
+		if assert.NotNil(t, err, test.source) {
+			assert.Equal(t, test.err, err.Error())
+		}
+	}
+}
+
+func Test_AssembleRoundTrip(t *testing.T) {
+	program := strings.Split(`21101,0,7,0,1105,1,10,104,10,99,109,1,2105,1,-1,72,105,32,116,104,101,114,101,10,-5,1,2`, ",")
+	listing, err := Disassemble(program)
+	assert.Nil(t, err)
+
+	var source []string
+	for _, line := range listing.Lines {
+		source = append(source, strings.SplitN(line.String(), ": ", 2)[1])
+	}
+	assembled, err := Assemble(strings.Join(source, "\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, program, assembled.Program)
+}
+
+func Test_ReadProgramFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "intcode")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	plain := filepath.Join(dir, "program.txt")
+	assert.Nil(t, ioutil.WriteFile(plain, []byte("104,7,99\n"), 0644))
+	program, symbols, err := ReadProgramFile(plain)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"104", "7", "99"}, program)
+	assert.Nil(t, symbols)
+
+	source := filepath.Join(dir, "program.asm")
+	assert.Nil(t, ioutil.WriteFile(source, []byte("start: OUT #7\n       HLT\n"), 0644))
+	program, symbols, err = ReadProgramFile(source)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"104", "7", "99"}, program)
+	assert.Equal(t, 0, symbols["start"])
+
+	_, _, err = ReadProgramFile(filepath.Join(dir, "missing"))
+	assert.NotNil(t, err)
+}
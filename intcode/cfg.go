@@ -0,0 +1,362 @@
+package intcode
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EdgeKind is the way control passes from one basic block to another.
+type EdgeKind int
+
+const (
+	FallThrough EdgeKind = iota // the next instruction follows the block
+	Jump                        // an unconditional jump
+	Branch                      // a conditional jump that is taken
+	Call                        // a jump to a function, after pushing a return address
+	CallReturn                  // from a call to its return address, where the function returns to
+)
+
+func (k EdgeKind) String() string {
+	switch k {
+	case FallThrough:
+		return "fall through"
+	case Jump:
+		return "jump"
+	case Branch:
+		return "branch"
+	case Call:
+		return "call"
+	case CallReturn:
+		return "call return"
+	}
+	return "unknown"
+}
+
+// Edge connects two basic blocks by their start addresses.
+type Edge struct {
+	From int
+	To   int
+	Kind EdgeKind
+}
+
+// Block is a basic block: instructions that always run in sequence, from Start up to End.
+type Block struct {
+	Start        int
+	End          int // one past the last cell of the last instruction
+	Instructions []*Instruction
+	Function     int  // the entry of the function the block belongs to
+	Return       bool // ends by jumping, maybe conditionally, to the return address at rb+0
+	Indirect     bool // ends with a jump whose target is read from memory and isn't a return
+	Halt         bool
+	SelfModified bool // the program writes to a cell of one of the instructions
+}
+
+// CallSite is a call found by the call idiom: an instruction stores an immediate return address at rb+0,
+// and an unconditional jump follows.
+type CallSite struct {
+	Addr   int // the address of the jump
+	Target int // -1 for calls through a function pointer
+	Return int
+}
+
+// SelfModification is an instruction that writes to a constant address inside another instruction.
+type SelfModification struct {
+	Addr        int // the address of the writing instruction
+	Target      int // the cell it writes
+	Instruction int // the address of the instruction the cell belongs to
+}
+
+// CFG is the control flow graph of a program, built from the code Disassemble finds.
+type CFG struct {
+	Blocks        []*Block // ordered by address
+	Edges         []Edge
+	Functions     []int // entry points: 0 and the targets of calls
+	Calls         []CallSite
+	SelfModifying []SelfModification
+	Symbols       map[string]int
+}
+
+// BuildCFG builds the control flow graph of program.
+func BuildCFG(program []string) (*CFG, error) {
+	return BuildCFGWithSymbols(program, nil)
+}
+
+// BuildCFGWithSymbols is like BuildCFG, but names functions and blocks after symbols, such as the ones
+// returned by Assemble.
+func BuildCFGWithSymbols(program []string, symbols map[string]int) (*CFG, error) {
+	d, err := newDisassembler(program, symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &CFG{Symbols: symbols}
+	addrs := make([]int, 0, len(d.decoded))
+	for addr := range d.decoded {
+		addrs = append(addrs, addr)
+	}
+	sort.Ints(addrs)
+
+	// writes to constant addresses that hold code
+	written := make(map[int]int)
+	for _, addr := range addrs {
+		inst := d.decoded[addr]
+		w, ok := opCodeWriteParams[inst.OpCode]
+		if !ok || inst.Params[w].Mode != 0 {
+			continue
+		}
+		target, err := strconv.Atoi(inst.Params[w].Value)
+		if err != nil || target < 0 || target >= len(d.owner) || d.owner[target] == -1 {
+			continue
+		}
+		written[addr] = target
+	}
+	targets := make(map[int]bool, len(written))
+	for _, target := range written {
+		targets[target] = true
+	}
+	modified := make(map[int]bool)
+	for _, addr := range addrs {
+		target, ok := written[addr]
+		if !ok {
+			continue
+		}
+		// the address of a write the program patches itself is only a placeholder
+		if targets[addr+1+opCodeWriteParams[d.decoded[addr].OpCode]] {
+			continue
+		}
+		owner := d.owner[target]
+		g.SelfModifying = append(g.SelfModifying, SelfModification{Addr: addr, Target: target, Instruction: owner})
+		modified[owner] = true
+	}
+
+	// split the code into blocks
+	var block *Block
+	blocks := make(map[int]*Block)
+	for _, addr := range addrs {
+		inst := d.decoded[addr]
+		_, target := d.jumps[addr]
+		if block == nil || block.End != addr || target || d.returns[addr] {
+			block = &Block{Start: addr, End: addr}
+			g.Blocks = append(g.Blocks, block)
+			blocks[addr] = block
+		}
+		block.Instructions = append(block.Instructions, inst)
+		block.End = addr + inst.Length
+		if modified[addr] {
+			block.SelfModified = true
+		}
+		if inst.OpCode == 5 || inst.OpCode == 6 || inst.OpCode == 99 {
+			block = nil
+		}
+	}
+
+	entries := map[int]bool{0: true}
+	for _, b := range g.Blocks {
+		last := b.Instructions[len(b.Instructions)-1]
+		next := b.End
+		fall := true
+		switch last.OpCode {
+		case 99:
+			b.Halt = true
+			fall = false
+		case 5, 6:
+			always := false
+			if cond, ok := d.immediate(last, 0); ok {
+				always = (cond != 0) == (last.OpCode == 5)
+				fall = !always
+				if !always {
+					// never taken
+					break
+				}
+			}
+			target, ok := d.immediate(last, 1)
+			if !ok {
+				if p := last.Params[1]; p.Mode == 2 && p.Value == "0" {
+					b.Return = true
+					break
+				}
+				b.Indirect = true
+				if always && g.isCall(d, b, next) {
+					g.Calls = append(g.Calls, CallSite{Addr: last.Addr, Target: -1, Return: next})
+					if blocks[next] != nil {
+						g.Edges = append(g.Edges, Edge{From: b.Start, To: next, Kind: CallReturn})
+					}
+				}
+				break
+			}
+			if blocks[target] == nil {
+				break
+			}
+			kind := Branch
+			if always {
+				kind = Jump
+				if g.isCall(d, b, next) {
+					kind = Call
+					g.Calls = append(g.Calls, CallSite{Addr: last.Addr, Target: target, Return: next})
+					entries[target] = true
+					if blocks[next] != nil {
+						g.Edges = append(g.Edges, Edge{From: b.Start, To: next, Kind: CallReturn})
+					}
+				}
+			}
+			g.Edges = append(g.Edges, Edge{From: b.Start, To: target, Kind: kind})
+		}
+		if fall && blocks[next] != nil {
+			g.Edges = append(g.Edges, Edge{From: b.Start, To: next, Kind: FallThrough})
+		}
+	}
+
+	for entry := range entries {
+		if blocks[entry] != nil {
+			g.Functions = append(g.Functions, entry)
+		}
+	}
+	sort.Ints(g.Functions)
+	g.assignFunctions(blocks)
+
+	return g, nil
+}
+
+// isCall reports whether b, which ends in an unconditional jump, pushes next as its return address.
+func (g *CFG) isCall(d *disassembler, b *Block, next int) bool {
+	for _, inst := range b.Instructions[:len(b.Instructions)-1] {
+		if v, ok := d.pushedReturn(inst); ok && v == next {
+			return true
+		}
+	}
+	return false
+}
+
+// assignFunctions puts each block in the first function, by address, that reaches it without calls.
+func (g *CFG) assignFunctions(blocks map[int]*Block) {
+	succs := make(map[int][]int)
+	for _, e := range g.Edges {
+		if e.Kind != Call {
+			succs[e.From] = append(succs[e.From], e.To)
+		}
+	}
+	assigned := make(map[int]bool)
+	for _, entry := range g.Functions {
+		queue := []int{entry}
+		for len(queue) > 0 {
+			addr := queue[0]
+			queue = queue[1:]
+			if assigned[addr] {
+				continue
+			}
+			assigned[addr] = true
+			blocks[addr].Function = entry
+			queue = append(queue, succs[addr]...)
+		}
+	}
+	// blocks only reached through jumps that can't be followed
+	for _, b := range g.Blocks {
+		if !assigned[b.Start] {
+			b.Function = b.Start
+		}
+	}
+}
+
+// Block returns the block holding addr, or nil if addr isn't code.
+func (g *CFG) Block(addr int) *Block {
+	i := sort.Search(len(g.Blocks), func(i int) bool { return g.Blocks[i].End > addr })
+	if i < len(g.Blocks) && g.Blocks[i].Start <= addr {
+		return g.Blocks[i]
+	}
+	return nil
+}
+
+// Label returns the symbol naming addr, or "" if there is none.
+func (g *CFG) Label(addr int) string {
+	label, _ := symbolAt(g.Symbols, addr)
+	return label
+}
+
+// name returns the label of addr for the graph.
+func (g *CFG) name(addr int) string {
+	if label := g.Label(addr); label != "" {
+		return fmt.Sprintf("%s (%d)", label, addr)
+	}
+	return strconv.Itoa(addr)
+}
+
+// dotQuote joins lines into a quoted DOT string.  Lines end in \l so they are left aligned.
+func dotQuote(lines []string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, line := range lines {
+		line = strings.Replace(line, `\`, `\\`, -1)
+		line = strings.Replace(line, `"`, `\"`, -1)
+		sb.WriteString(line)
+		sb.WriteString(`\l`)
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language, with a cluster for each function.  Calls are
+// dashed, returns to the caller are dotted, and self modified blocks are red.
+func (g *CFG) WriteDOT(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	printf("digraph cfg {\n")
+	printf("  node [shape=box, fontname=\"monospace\"];\n")
+
+	byFunction := make(map[int][]*Block)
+	for _, b := range g.Blocks {
+		byFunction[b.Function] = append(byFunction[b.Function], b)
+	}
+	functions := make([]int, 0, len(byFunction))
+	for f := range byFunction {
+		functions = append(functions, f)
+	}
+	sort.Ints(functions)
+
+	for _, f := range functions {
+		printf("  subgraph cluster_%d {\n", f)
+		printf("    label=%q;\n", g.name(f))
+		for _, b := range byFunction[f] {
+			lines := make([]string, 0, len(b.Instructions)+1)
+			if label := g.Label(b.Start); label != "" {
+				lines = append(lines, label+":")
+			}
+			for _, inst := range b.Instructions {
+				lines = append(lines, fmt.Sprintf("%6d: %s", inst.Addr, inst))
+			}
+			attrs := ""
+			switch {
+			case b.SelfModified:
+				attrs = ", color=red"
+			case b.Return:
+				attrs = ", peripheries=2"
+			case b.Halt:
+				attrs = ", style=bold"
+			}
+			printf("    b%d [label=%s%s];\n", b.Start, dotQuote(lines), attrs)
+		}
+		printf("  }\n")
+	}
+
+	for _, e := range g.Edges {
+		attrs := ""
+		switch e.Kind {
+		case Branch:
+			attrs = " [label=\"taken\"]"
+		case Call:
+			attrs = " [style=dashed]"
+		case CallReturn:
+			attrs = " [style=dotted]"
+		}
+		printf("  b%d -> b%d%s;\n", e.From, e.To, attrs)
+	}
+	printf("}\n")
+	return err
+}
@@ -0,0 +1,74 @@
+package intcode
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// calls a function twice, and patches the operand of an output
+const cfgProgram = `
+main:   ARB  #100
+        ADD  #1, #0, rb+1
+        ADD  #.r1, #0, rb+0
+        JNZ  #1, #double
+.r1:    OUT  rb+1
+        ADD  #.r2, #0, rb+0
+        JNZ  #1, #double
+.r2:    ADD  rb+1, #0, [patch+1]
+patch:  OUT  #0
+        HLT
+double: ADD  rb+1, rb+1, rb+1
+.loop:  LT   rb+1, #10, [t]
+        JZ   [t], #.done
+        MUL  rb+1, #2, rb+1
+        JZ   #0, #.loop
+.done:  JZ   #0, rb+0
+t:      DATA 0
+`
+
+func Test_CFG(t *testing.T) {
+	assembled, err := Assemble(cfgProgram)
+	assert.Nil(t, err)
+	sym := assembled.Symbols
+
+	g, err := BuildCFGWithSymbols(assembled.Program, sym)
+	assert.Nil(t, err)
+
+	starts := make([]int, len(g.Blocks))
+	for i, b := range g.Blocks {
+		starts[i] = b.Start
+	}
+	assert.Equal(t, []int{0, sym["main.r1"], sym["main.r2"], sym["double"], sym["double.loop"], sym["double.loop"] + 7, sym["double.done"]}, starts)
+
+	assert.Equal(t, []int{0, sym["double"]}, g.Functions)
+	assert.Equal(t, []CallSite{
+		{Addr: sym["main.r1"] - 3, Target: sym["double"], Return: sym["main.r1"]},
+		{Addr: sym["main.r2"] - 3, Target: sym["double"], Return: sym["main.r2"]},
+	}, g.Calls)
+	assert.Equal(t, []SelfModification{{Addr: sym["main.r2"], Target: sym["patch"] + 1, Instruction: sym["patch"]}}, g.SelfModifying)
+
+	assert.Contains(t, g.Edges, Edge{From: 0, To: sym["double"], Kind: Call})
+	assert.Contains(t, g.Edges, Edge{From: 0, To: sym["main.r1"], Kind: CallReturn})
+	assert.Contains(t, g.Edges, Edge{From: sym["double.loop"], To: sym["double.done"], Kind: Branch})
+	assert.Contains(t, g.Edges, Edge{From: sym["double.loop"], To: sym["double.loop"] + 7, Kind: FallThrough})
+	assert.Contains(t, g.Edges, Edge{From: sym["double.loop"] + 7, To: sym["double.loop"], Kind: Jump})
+	assert.Contains(t, g.Edges, Edge{From: sym["double"], To: sym["double.loop"], Kind: FallThrough})
+
+	done := g.Block(sym["double.done"])
+	assert.True(t, done.Return)
+	assert.Equal(t, sym["double"], done.Function)
+	assert.True(t, g.Block(sym["patch"]).SelfModified)
+	assert.True(t, g.Block(sym["patch"]).Halt)
+	assert.Equal(t, 0, g.Block(sym["patch"]).Function)
+	assert.Nil(t, g.Block(sym["t"]))
+
+	var dot bytes.Buffer
+	assert.Nil(t, g.WriteDOT(&dot))
+	assert.True(t, strings.HasPrefix(dot.String(), "digraph cfg {\n"))
+	assert.Contains(t, dot.String(), `subgraph cluster_`+strconv.Itoa(sym["double"])+` {`)
+	assert.Contains(t, dot.String(), `label="double (`+strconv.Itoa(sym["double"])+`)";`)
+	assert.Contains(t, dot.String(), `b0 -> b`+strconv.Itoa(sym["double"])+` [style=dashed];`)
+}
@@ -0,0 +1,305 @@
+package intcode
+
+import (
+	"context"
+	"errors"
+	"math/big"
+)
+
+// ErrBudgetExhausted is returned by ExecuteContext when the instruction budget runs out before the
+// program halts.  Raising the budget and executing again resumes the program where it stopped.
+var ErrBudgetExhausted = errors.New("instruction budget exhausted")
+
+// Memory is the snapshot format used before versioned snapshots.  Load still reads it.
+type Memory struct {
+	Ptr          int      `json:"ptr"`
+	Program      []string `json:"program"`
+	RelativeBase int      `json:"relativeBase"`
+	LastOut      string   `json:"lastOut"`
+}
+
+type IntCodeComputer struct {
+	opCodes       map[int]int
+	writeParams   map[int]int
+	extensions    map[int]*Extension
+	origMemory    []int64
+	origWide      map[int]*big.Int
+	memory        *pagedMemory
+	wide          map[int]*big.Int // cells that only fit with arbitrary precision
+	bigMode       bool
+	compiled      bool
+	origCode      *compiledCode // instructions compiled from the original program
+	decoded       map[decodedKey]*compiledOp
+	ptr           int
+	relativeBase  int
+	lastOut       string
+	prompt        chan string
+	in            chan string
+	out           chan string
+	quit          chan<- string
+	processed     chan struct{}
+	pauseOnOutput bool
+	inputPrompt   *string
+	inDev         InputDevice
+	outDev        OutputDevice
+	undelivered   bool // the output device didn't take the last output, so Execute delivers it first
+	origErr       error
+	progErr       error
+	err           error
+	instructions  uint64
+	budget        uint64
+	hooks         []*Hooks
+	// state for the step API
+	instructionPtr int
+	input          []string
+	output         int64
+	outputs        []int64
+}
+
+// NewIntCodeComputer creates a computer for program.  Values are read from in and written to out,
+// and the last output is sent on quit when the program halts.  If pauseOnOutput is set, the computer
+// waits for OutputProcessed to be called after each output.  If inputPrompt is not nil, it is sent on
+// the prompt channel before every input instruction.
+//
+// Memory is kept as int64.  Arbitrary precision mode is switched on automatically when the program
+// contains a value that does not fit in 64 bits, and can be toggled with SetBigIntMode.
+func NewIntCodeComputer(program []string, in chan string, out chan string, quit chan<- string, pauseOnOutput bool, inputPrompt *string) *IntCodeComputer {
+	c := new(IntCodeComputer)
+	c.origMemory, c.origWide, c.origErr = parseProgram(program)
+	c.bigMode = len(c.origWide) > 0
+	c.Reset()
+	c.prompt = make(chan string, 1)
+	c.processed = make(chan struct{}, 1)
+	c.in = in
+	c.out = out
+	c.quit = quit
+	c.pauseOnOutput = pauseOnOutput
+	c.inputPrompt = inputPrompt
+	c.inDev = channelInput{c}
+	c.outDev = channelOutput{c}
+	c.opCodes = opCodeLengths
+	c.writeParams = opCodeWriteParams
+	return c
+}
+
+// op code to number of bytes including op code and parameters
+var opCodeLengths = map[int]int{
+	1:  4, // add first 2 params, set 3 param location to value
+	2:  4, // multiply first 2 params, set 3 param location to value
+	3:  2, // read string
+	4:  2, // output string
+	5:  3, // if param 0 is not 0, jump to param 1 location
+	6:  3, // if param 0 is 0, jump to param 1 location
+	7:  4, // if param 0 is less than param 1, set location at param 3 to 1, else set it to 0
+	8:  4, // if param 0 is equal to param 1, set location at param 3 to 1, else set it to 0
+	9:  2, // set relative base
+	99: 0, // halt
+}
+
+// op code to the index of the parameter it writes to
+var opCodeWriteParams = map[int]int{
+	1: 2,
+	2: 2,
+	3: 0,
+	7: 2,
+	8: 2,
+}
+
+// SetBigIntMode switches arbitrary precision arithmetic on or off.  When it is off, an add or multiply
+// whose result doesn't fit in 64 bits faults with ErrOverflow.
+func (c *IntCodeComputer) SetBigIntMode(enabled bool) {
+	c.bigMode = enabled
+}
+
+func (c *IntCodeComputer) BigIntMode() bool {
+	return c.bigMode
+}
+
+func (c *IntCodeComputer) GetPromptChannel() <-chan string {
+	return c.prompt
+}
+
+func (c *IntCodeComputer) OutputProcessed() {
+	c.processed <- struct{}{}
+}
+
+// SetInstructionBudget limits the number of instructions the computer will execute.  A budget of 0
+// means no limit.  The count is kept across runs and cleared by Reset.
+func (c *IntCodeComputer) SetInstructionBudget(budget uint64) {
+	c.budget = budget
+}
+
+// Instructions returns the number of instructions executed since the last Reset.
+func (c *IntCodeComputer) Instructions() uint64 {
+	return c.instructions
+}
+
+// GetProgram returns the current memory in the string program format.  The format has a cell for every
+// address, so it fails with ErrSparseMemory if a program has written far beyond its code; MemoryRuns
+// returns just the cells in use.
+func (c *IntCodeComputer) GetProgram() ([]string, error) {
+	return c.formatMemory()
+}
+
+// Err returns the fault that stopped the last Execute, or nil if the program halted normally.
+func (c *IntCodeComputer) Err() error {
+	return c.err
+}
+
+// Clone returns an independent copy of the computer's program state: memory, ptr, relative base, last
+// output and instruction count.  The clone has no channels, devices or hooks and no queued input or
+// output, so it is driven with the step API, or executed after SetDevices.  Memory is shared copy on
+// write, so clones are cheap and only pay for the pages they change.  Clone must not be called while the computer is executing.
+func (c *IntCodeComputer) Clone() *IntCodeComputer {
+	clone := new(IntCodeComputer)
+	clone.opCodes = c.opCodes
+	clone.writeParams = c.writeParams
+	clone.extensions = c.extensions
+	clone.origMemory = c.origMemory
+	clone.origWide = c.origWide
+	clone.origErr = c.origErr
+	clone.memory = c.memory.clone()
+	if c.wide != nil {
+		clone.wide = make(map[int]*big.Int, len(c.wide))
+		for pos, b := range c.wide {
+			clone.wide[pos] = b
+		}
+	}
+	clone.bigMode = c.bigMode
+	clone.compiled = c.compiled
+	if c.origCode != nil {
+		clone.origCode = c.origCode.clone()
+	}
+	clone.ptr = c.ptr
+	clone.relativeBase = c.relativeBase
+	clone.lastOut = c.lastOut
+	clone.output = c.output
+	clone.instructions = c.instructions
+	clone.instructionPtr = c.instructionPtr
+	clone.budget = c.budget
+	clone.progErr = c.progErr
+	clone.prompt = make(chan string, 1)
+	clone.processed = make(chan struct{}, 1)
+	clone.SetDevices(nil, nil)
+	return clone
+}
+
+// Reset restores the original program and clears the registers so that Execute can be run again.
+func (c *IntCodeComputer) Reset() {
+	c.memory = newPagedMemory(c.origMemory)
+	if c.origCode != nil {
+		// the instructions compiled from the original program are still valid
+		c.memory.code = c.origCode.clone()
+	}
+	c.wide = nil
+	for pos, b := range c.origWide {
+		c.setBig(pos, b)
+	}
+	c.ptr = 0
+	c.relativeBase = 0
+	c.lastOut = ""
+	c.instructions = 0
+	c.input = nil
+	c.outputs = nil
+	c.undelivered = false
+	c.output = 0
+	c.progErr = c.origErr
+	c.err = nil
+}
+
+// Execute runs the program until it halts or faults.  Either way the last output is sent on the quit
+// channel, if there is one, so hosts waiting on it are released; a fault is returned as a *Fault and
+// is also available from Err.
+func (c *IntCodeComputer) Execute() error {
+	return c.ExecuteContext(context.Background())
+}
+
+// ExecuteContext is like Execute, but stops when ctx is cancelled, even while waiting for input or for
+// an output to be taken, and returns ctx.Err().  After a cancellation the last output is only sent on
+// quit if the channel has room for it.
+func (c *IntCodeComputer) ExecuteContext(ctx context.Context) error {
+	err := c.progErr
+	if err == nil {
+		err = c.execute(ctx)
+	}
+	c.err = err
+	if c.quit == nil {
+		return err
+	}
+	if ctx.Err() == nil {
+		select {
+		case c.quit <- c.lastOut:
+		case <-ctx.Done():
+		}
+	} else {
+		select {
+		case c.quit <- c.lastOut:
+		default:
+		}
+	}
+	return err
+}
+
+func (c *IntCodeComputer) execute(ctx context.Context) error {
+	done := ctx.Done()
+
+	if c.undelivered {
+		if err := c.deliver(ctx); err != nil {
+			return err
+		}
+	}
+
+	for {
+		if c.budget > 0 && c.instructions >= c.budget {
+			return ErrBudgetExhausted
+		}
+		if c.instructions&1023 == 0 && done != nil {
+			select {
+			case <-done:
+				return ctx.Err()
+			default:
+			}
+		}
+
+		status, err := c.step()
+		switch status {
+		case Halted:
+			return nil
+		case Faulted:
+			return err
+		case Paused:
+			return ErrPaused
+		case Yielded:
+			if done != nil {
+				select {
+				case <-done:
+					return ctx.Err()
+				default:
+				}
+			}
+		case NeedsInput:
+			value, err := c.inDev.Input(ctx)
+			if err != nil {
+				return err
+			}
+			c.input = append(c.input, value)
+		case ProducedOutput:
+			if err := c.deliver(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// deliver sends the last output to the output device.  The output instruction has already been
+// executed, so an output the device doesn't take is kept and delivered when executing again, rather
+// than executing the instruction twice.
+func (c *IntCodeComputer) deliver(ctx context.Context) error {
+	err := c.outDev.Output(ctx, c.lastOut)
+	if d, ok := err.(*delivered); ok {
+		c.undelivered = false
+		return d.err
+	}
+	c.undelivered = err != nil
+	return err
+}
@@ -0,0 +1,374 @@
+package intcode
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// programOf returns c's memory in the string program format.
+func programOf(c *IntCodeComputer) []string {
+	program, err := c.GetProgram()
+	if err != nil {
+		panic(err)
+	}
+	return program
+}
+
+func runProgram(program string, inputs ...string) (outputs []string, lastOut string) {
+	in := make(chan string, len(inputs)+1)
+	out := make(chan string, 1)
+	quit := make(chan string, 1)
+
+	for _, i := range inputs {
+		in <- i
+	}
+
+	c := NewIntCodeComputer(strings.Split(program, ","), in, out, quit, true, nil)
+	go c.Execute()
+
+	for {
+		select {
+		case o := <-out:
+			outputs = append(outputs, o)
+			c.OutputProcessed()
+		case lastOut = <-quit:
+			return
+		}
+	}
+}
+
+func Test_Quine(t *testing.T) {
+	program := `109,1,204,-1,1001,100,1,100,1008,100,16,101,1006,101,0,99`
+	outputs, lastOut := runProgram(program)
+	assert.Equal(t, program, strings.Join(outputs, ","))
+	assert.Equal(t, "99", lastOut)
+}
+
+func Test_LargeNumbers(t *testing.T) {
+	outputs, _ := runProgram(`1102,34915192,34915192,7,4,7,99,0`)
+	assert.Equal(t, []string{"1219070632396864"}, outputs)
+
+	outputs, _ = runProgram(`104,1125899906842624,99`)
+	assert.Equal(t, []string{"1125899906842624"}, outputs)
+}
+
+func Test_Prompt(t *testing.T) {
+	in := make(chan string, 1)
+	out := make(chan string, 1)
+	quit := make(chan string, 1)
+	prompt := "input:"
+
+	c := NewIntCodeComputer(strings.Split(`3,0,4,0,99`, ","), in, out, quit, true, &prompt)
+	go c.Execute()
+
+	assert.Equal(t, "input:", <-c.GetPromptChannel())
+	in <- "42"
+	assert.Equal(t, "42", <-out)
+	c.OutputProcessed()
+	assert.Equal(t, "42", <-quit)
+}
+
+func Test_Reset(t *testing.T) {
+	in := make(chan string, 2)
+	out := make(chan string, 1)
+	quit := make(chan string, 1)
+
+	// outputs 1 if the input equals 8, otherwise 0
+	c := NewIntCodeComputer(strings.Split(`3,9,8,9,10,9,4,9,99,-1,8`, ","), in, out, quit, false, nil)
+
+	for _, test := range []struct {
+		input    string
+		expected string
+	}{
+		{"8", "1"},
+		{"7", "0"},
+		{"8", "1"},
+	} {
+		c.Reset()
+		go c.Execute()
+		in <- test.input
+		assert.Equal(t, test.expected, <-out)
+		<-quit
+	}
+}
+
+func Test_BigIntMode(t *testing.T) {
+	// squares 2^62 and outputs the result
+	program := `2,9,9,9,4,9,99,0,0,4611686018427387904`
+
+	in := make(chan string, 1)
+	out := make(chan string, 1)
+	quit := make(chan string, 1)
+
+	c := NewIntCodeComputer(strings.Split(program, ","), in, out, quit, false, nil)
+	assert.False(t, c.BigIntMode())
+	err := c.Execute()
+	assert.True(t, errors.Is(err, ErrOverflow))
+	assert.Equal(t, 0, err.(*Fault).Ptr)
+	assert.Equal(t, "4611686018427387904", programOf(c)[9])
+	<-quit
+
+	c.Reset()
+	c.SetBigIntMode(true)
+	go c.Execute()
+	assert.Equal(t, "21267647932558653966460912964485513216", <-out)
+	<-quit
+
+	assert.Equal(t, "21267647932558653966460912964485513216", programOf(c)[9])
+}
+
+func Test_WideLiteral(t *testing.T) {
+	outputs, _ := runProgram(`1,7,8,9,4,9,99,99999999999999999999,1,0`)
+	assert.Equal(t, []string{"100000000000000000000"}, outputs)
+}
+
+func Test_SparseMemory(t *testing.T) {
+	in := make(chan string, 1)
+	out := make(chan string, 1)
+	quit := make(chan string, 1)
+
+	c := NewIntCodeComputer(strings.Split(`1101,42,0,1000000000,4,1000000000,99`, ","), in, out, quit, false, nil)
+	go c.Execute()
+	assert.Equal(t, "42", <-out)
+	<-quit
+
+	stats := c.MemoryStats()
+	assert.Equal(t, 1000000001, stats.Length)
+	assert.Equal(t, 2, stats.Pages)
+	assert.True(t, stats.Bytes < 1<<20)
+}
+
+func Test_Faults(t *testing.T) {
+	table := []struct {
+		program  string
+		expected error
+		ptr      int
+	}{
+		{`1,0,0,0,42`, ErrInvalidOpcode, 4},
+		{`1,0,0,0,99,x`, ErrUnparsableCell, 5},
+		{`301,0,0,0,99`, ErrInvalidParameterMode, 0},
+		{`1,-1,0,0,99`, ErrNegativeAddress, 0},
+		{`109,-5,2201,0,0,0,99`, ErrNegativeAddress, 2},
+		{`11101,1,1,3,99`, ErrImmediateWrite, 0},
+		{`103,0,99`, ErrImmediateWrite, 0},
+		{`1106,0,-3,99`, ErrNegativeAddress, -3},
+		{`1101,1,1,9223372036854775807,99`, ErrAddress, 0},
+		{`109,9223372036854775807,21101,1,1,0,99`, ErrAddress, 2},
+		{`3,0,99`, ErrUnparsableCell, 0},
+	}
+
+	for _, test := range table {
+		in := make(chan string, 1)
+		out := make(chan string, 1)
+		quit := make(chan string, 1)
+		in <- "not a number"
+
+		c := NewIntCodeComputer(strings.Split(test.program, ","), in, out, quit, false, nil)
+		err := c.Execute()
+		<-quit
+
+		assert.True(t, errors.Is(err, test.expected), "%s: %v", test.program, err)
+		assert.Equal(t, err, c.Err())
+
+		var fault *Fault
+		if assert.True(t, errors.As(err, &fault)) {
+			assert.Equal(t, test.ptr, fault.Ptr, test.program)
+		}
+	}
+}
+
+func Test_InputFaults(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		expected error
+	}{
+		{"not a number", ErrUnparsableCell},
+		{"100000000000000000000", ErrOverflow},
+	} {
+		in := make(chan string, 1)
+		quit := make(chan string, 1)
+		in <- test.input
+
+		c := NewIntCodeComputer(strings.Split(`3,0,99`, ","), in, nil, quit, false, nil)
+		err := c.Execute()
+		<-quit
+		assert.True(t, errors.Is(err, test.expected), "%s: %v", test.input, err)
+	}
+}
+
+func Test_InstructionBudget(t *testing.T) {
+	quit := make(chan string, 1)
+
+	// jumps to itself forever
+	c := NewIntCodeComputer(strings.Split(`1105,1,0`, ","), nil, nil, quit, false, nil)
+	c.SetInstructionBudget(1000)
+	err := c.Execute()
+	<-quit
+	assert.Equal(t, ErrBudgetExhausted, err)
+	assert.Equal(t, uint64(1000), c.Instructions())
+
+	c.SetInstructionBudget(1500)
+	assert.Equal(t, ErrBudgetExhausted, c.Execute())
+	<-quit
+	assert.Equal(t, uint64(1500), c.Instructions())
+}
+
+func Test_FaultsNotCounted(t *testing.T) {
+	// each program runs one instruction, then faults on the second
+	table := []struct {
+		program  string
+		input    string
+		expected error
+	}{
+		{`1101,0,0,20,1101,9223372036854775807,1,0,99`, "", ErrOverflow},
+		{`1101,0,0,20,3,0,99`, "100000000000000000000", ErrOverflow},
+		{`1101,0,0,20,5,4,9,99,0,99999999999999999999`, "", ErrAddressOverflow},
+		{`1101,0,0,20,9,7,99,99999999999999999999`, "", ErrAddressOverflow},
+	}
+
+	for _, test := range table {
+		in := make(chan string, 1)
+		quit := make(chan string, 1)
+		in <- test.input
+
+		c := NewIntCodeComputer(strings.Split(test.program, ","), in, nil, quit, false, nil)
+		err := c.Execute()
+		<-quit
+		assert.True(t, errors.Is(err, test.expected), "%s: %v", test.program, err)
+		assert.Equal(t, uint64(1), c.Instructions(), test.program)
+	}
+}
+
+func Test_Cancel(t *testing.T) {
+	in := make(chan string)
+	out := make(chan string)
+	quit := make(chan string, 1)
+	prompt := "input"
+
+	c := NewIntCodeComputer(strings.Split(`3,0,4,0,99`, ","), in, out, quit, true, &prompt)
+
+	// blocked on input: the prompt is sent just before waiting for it
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.ExecuteContext(ctx) }()
+	<-c.GetPromptChannel()
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+	<-quit
+
+	// blocked on output: a budget stops it after the input, then nothing reads out and the cancelled
+	// context stops it at the output
+	c.SetInstructionBudget(1)
+	go func() { done <- c.Execute() }()
+	<-c.GetPromptChannel()
+	in <- "7"
+	assert.Equal(t, ErrBudgetExhausted, <-done)
+	<-quit
+	c.SetInstructionBudget(0)
+	assert.Equal(t, context.Canceled, c.ExecuteContext(ctx))
+	<-quit
+	assert.Equal(t, 4, c.ptr)
+
+	// the output that wasn't taken is delivered when executing again
+	go func() { done <- c.ExecuteContext(context.Background()) }()
+	assert.Equal(t, "7", <-out)
+	c.OutputProcessed()
+	assert.Nil(t, <-done)
+	assert.Equal(t, "7", <-quit)
+
+	// runaway program, stopped by a budget and then cancelled: the context is checked every 1024 instructions
+	c = NewIntCodeComputer(strings.Split(`1105,1,0`, ","), nil, nil, quit, false, nil)
+	c.SetInstructionBudget(5000)
+	assert.Equal(t, ErrBudgetExhausted, c.Execute())
+	<-quit
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	c.SetInstructionBudget(0)
+	assert.Equal(t, context.Canceled, c.ExecuteContext(ctx))
+	<-quit
+	assert.Equal(t, uint64(5120), c.Instructions())
+}
+
+func Test_Clone(t *testing.T) {
+	// echoes every input doubled, forever
+	c := NewIntCodeComputer(strings.Split(`3,11,1002,11,2,11,4,11,1105,1,0,0`, ","), nil, nil, nil, false, nil)
+	c.ProvideInput(1)
+	c.RunUntilOutput()
+	c.ProvideInput(99)
+
+	clone := c.Clone()
+	assert.Equal(t, c.Registers(), clone.Registers())
+	assert.Equal(t, 1, clone.MemoryStats().Shared)
+
+	clone.ProvideInput(5)
+	status, _ := clone.RunUntilOutput()
+	assert.Equal(t, ProducedOutput, status)
+	assert.Equal(t, int64(10), clone.Output())
+	assert.Equal(t, 0, clone.MemoryStats().Shared)
+
+	// the clone had no queued input, and its writes didn't reach the original
+	c.RunUntilOutput()
+	assert.Equal(t, int64(198), c.Output())
+	assert.Equal(t, int64(10), clone.Peek(11))
+
+	clone.Reset()
+	assert.Equal(t, strings.Split(`3,11,1002,11,2,11,4,11,1105,1,0,0`, ","), programOf(clone))
+}
+
+func Test_CloneSearch(t *testing.T) {
+	// a lock that checks one digit at a time, outputting 1 for each right digit and 2 when it opens
+	assembled, err := Assemble(`
+MACRO check digit
+        IN   [x]
+        EQ   [x], #digit, [ok]
+        OUT  [ok]
+        JZ   [ok], #fail
+ENDM
+        check 3
+        check 1
+        check 4
+        check 1
+        OUT  #2
+fail:   HLT
+x:      DATA 0
+ok:     DATA 0
+`)
+	assert.Nil(t, err)
+
+	type state struct {
+		c    *IntCodeComputer
+		code []int64
+	}
+
+	start := NewIntCodeComputer(assembled.Program, nil, nil, nil, false, nil)
+	queue := []state{{c: start}}
+	var code []int64
+	clones := 0
+
+search:
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for d := int64(0); d < 10; d++ {
+			next := s.c.Clone()
+			clones++
+			next.ProvideInput(d)
+			if status, _ := next.RunUntilOutput(); status != ProducedOutput || next.Output() != 1 {
+				continue
+			}
+			nextCode := append(append([]int64(nil), s.code...), d)
+			if status, _ := next.RunUntilOutput(); status == ProducedOutput && next.Output() == 2 {
+				code = nextCode
+				break search
+			}
+			queue = append(queue, state{c: next, code: nextCode})
+		}
+	}
+
+	assert.Equal(t, []int64{3, 1, 4, 1}, code)
+	// every digit is tried at each level, until the last one opens the lock
+	assert.Equal(t, 10+10+10+2, clones)
+}
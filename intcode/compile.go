@@ -0,0 +1,403 @@
+package intcode
+
+// maxInstructionLength is the number of cells in the longest instruction, so a write to a cell can
+// only change instructions starting at most this many cells before it.
+const maxInstructionLength = 4
+
+// param is a decoded instruction parameter.
+type param struct {
+	mode  int
+	value int // the address in position mode, the parameter's own address in immediate mode, or the offset in relative mode
+}
+
+// compiledOp is an instruction decoded once so it can be executed again without decoding.
+type compiledOp struct {
+	length int
+	exec   func(c *IntCodeComputer, op *compiledOp) (Status, error)
+	params []param
+	buf    [maxInstructionLength - 1]param
+}
+
+// maxCompiledAddr bounds the addresses that are compiled, so programs that scatter code over a sparse
+// memory can't grow the cache without bound.  Code above it is interpreted.
+const maxCompiledAddr = 1 << 20
+
+// decodedKey identifies an instruction by its address and cells, which is all decoding depends on.
+type decodedKey struct {
+	ptr   int
+	cells [maxInstructionLength]int64
+}
+
+// maxDecoded bounds the number of decoded instructions a computer remembers.
+const maxDecoded = 1 << 14
+
+// codePage holds the compiled instructions starting in a page of memory, and covers counts the cached
+// instructions each cell is part of, so writes to cells that aren't code are cheap to check.
+type codePage struct {
+	ops    [pageSize]*compiledOp
+	covers [pageSize]uint8
+}
+
+// compiledCode caches the compiled instructions of a memory by address.  Like memory, it is made of
+// pages that are shared copy on write with the caches of clones, and of the memory after a Reset.
+// Pages are indexed by page number, which maxCompiledAddr keeps small.
+type compiledCode struct {
+	pages []*codePage
+	owned []bool
+}
+
+// clone returns a cache that shares all of cc's pages.  Neither cache owns them afterwards, so
+// whichever changes a page first copies it.
+func (cc *compiledCode) clone() *compiledCode {
+	for i := range cc.owned {
+		cc.owned[i] = false
+	}
+	return &compiledCode{
+		pages: append([]*codePage(nil), cc.pages...),
+		owned: make([]bool, len(cc.pages)),
+	}
+}
+
+// writablePage returns the page holding pos, allocating it, or copying it if it is shared.
+func (cc *compiledCode) writablePage(pos int) *codePage {
+	idx := pos >> pageBits
+	for len(cc.pages) <= idx {
+		cc.pages = append(cc.pages, nil)
+		cc.owned = append(cc.owned, false)
+	}
+	if cc.owned[idx] {
+		return cc.pages[idx]
+	}
+	p := new(codePage)
+	if cc.pages[idx] != nil {
+		*p = *cc.pages[idx]
+	}
+	cc.pages[idx] = p
+	cc.owned[idx] = true
+	return p
+}
+
+func (cc *compiledCode) lookup(ptr int) *compiledOp {
+	if idx := ptr >> pageBits; ptr >= 0 && idx < len(cc.pages) && cc.pages[idx] != nil {
+		return cc.pages[idx].ops[ptr&pageMask]
+	}
+	return nil
+}
+
+func (cc *compiledCode) covered(pos int) bool {
+	if idx := pos >> pageBits; idx < len(cc.pages) && cc.pages[idx] != nil {
+		return cc.pages[idx].covers[pos&pageMask] > 0
+	}
+	return false
+}
+
+func (cc *compiledCode) add(ptr int, op *compiledOp) {
+	end := ptr + op.length
+	if op.length == 0 {
+		end = ptr + 1
+	}
+	cc.writablePage(ptr).ops[ptr&pageMask] = op
+	for i := ptr; i < end; i++ {
+		cc.writablePage(i).covers[i&pageMask]++
+	}
+}
+
+// invalidate drops the cached instructions that include pos, because pos is being written.
+func (cc *compiledCode) invalidate(pos int) {
+	if !cc.covered(pos) {
+		return
+	}
+	for start := pos - maxInstructionLength + 1; start <= pos; start++ {
+		op := cc.lookup(start)
+		if op == nil {
+			continue
+		}
+		end := start + op.length
+		if op.length == 0 {
+			end = start + 1
+		}
+		if end <= pos {
+			continue
+		}
+		cc.writablePage(start).ops[start&pageMask] = nil
+		for i := start; i < end; i++ {
+			cc.writablePage(i).covers[i&pageMask]--
+		}
+	}
+}
+
+// SetCompiled switches the compiled engine on or off.  The compiled engine decodes each instruction the
+// first time it is executed and reuses the decoded instruction afterwards, until the program writes to
+// it.  Results are the same as the interpreter's.  Instructions are still interpreted while hooks are
+// attached, and when they would fault.
+func (c *IntCodeComputer) SetCompiled(enabled bool) {
+	c.compiled = enabled
+	if !enabled {
+		c.memory.code = nil
+		c.origCode = nil
+		c.decoded = nil
+	}
+}
+
+func (c *IntCodeComputer) Compiled() bool {
+	return c.compiled
+}
+
+// stepCompiled executes the instruction at ptr with the compiled engine.  It returns false if the
+// instruction can't be compiled, so it has to be interpreted.
+func (c *IntCodeComputer) stepCompiled() (Status, error, bool) {
+	m := c.memory
+	if m.code == nil {
+		m.code = new(compiledCode)
+	}
+	op := m.code.lookup(c.ptr)
+	if op == nil {
+		if c.ptr >= maxCompiledAddr {
+			return Running, nil, false
+		}
+		op = c.compile(c.ptr)
+		if op == nil {
+			return Running, nil, false
+		}
+		m.code.add(c.ptr, op)
+		if c.original(c.ptr, op) {
+			if c.origCode == nil {
+				c.origCode = new(compiledCode)
+			}
+			if c.origCode.lookup(c.ptr) == nil {
+				c.origCode.add(c.ptr, op)
+			}
+		}
+	}
+	instructions, instructionPtr := c.instructions, c.instructionPtr
+	status, err := op.exec(c, op)
+	if status == Faulted {
+		// like the interpreter, an instruction that faults isn't counted
+		c.instructions, c.instructionPtr = instructions, instructionPtr
+	}
+	return status, err, true
+}
+
+// original reports whether the cells of op at ptr still hold the original program, so Reset can keep
+// it.
+func (c *IntCodeComputer) original(ptr int, op *compiledOp) bool {
+	end := ptr + op.length
+	if op.length == 0 {
+		end = ptr + 1
+	}
+	if end > len(c.origMemory) {
+		return false
+	}
+	for pos := ptr; pos < end; pos++ {
+		if _, ok := c.origWide[pos]; ok || c.getValue(pos) != c.origMemory[pos] {
+			return false
+		}
+	}
+	return true
+}
+
+// compile decodes the instruction at ptr, or returns nil if it can't be compiled because it would
+// fault or uses values that need arbitrary precision.  Decoded instructions are remembered, so code the
+// program rewrites back and forth, or that is rewritten again after a Reset, is only decoded once.
+func (c *IntCodeComputer) compile(ptr int) *compiledOp {
+	if ptr < 0 || c.isWide(ptr) {
+		return nil
+	}
+	instruction := c.getValue(ptr)
+	if instruction < 0 || instruction > 99999 {
+		return nil
+	}
+	opCode := int(instruction % 100)
+	length, ok := c.opCodes[opCode]
+	if !ok {
+		return nil
+	}
+	exec := compiledOps[opCode]
+	if exec == nil {
+		return nil
+	}
+	key := decodedKey{ptr: ptr}
+	for i := 0; i < length; i++ {
+		if c.isWide(ptr + i) {
+			return nil
+		}
+		key.cells[i] = c.getValue(ptr + i)
+	}
+	if op, ok := c.decoded[key]; ok {
+		return op
+	}
+	op := &compiledOp{length: length, exec: exec}
+	if length > 0 {
+		op.params = op.buf[:length-1]
+	}
+	modes := int(instruction / 100)
+	for i := range op.params {
+		pos := ptr + i + 1
+		p := param{mode: modes % 10}
+		modes /= 10
+		switch p.mode {
+		case 0:
+			p.value = int(c.getValue(pos))
+			if p.value < 0 || p.value > MaxAddress {
+				return nil
+			}
+		case 1:
+			if w, ok := c.writeParams[opCode]; ok && w == i {
+				return nil
+			}
+			p.value = pos
+		case 2:
+			p.value = int(c.getValue(pos))
+		default:
+			return nil
+		}
+		op.params[i] = p
+	}
+	if c.decoded == nil || len(c.decoded) >= maxDecoded {
+		c.decoded = make(map[decodedKey]*compiledOp)
+	}
+	c.decoded[key] = op
+	return op
+}
+
+// resolve returns the addresses of the instruction's parameters.
+func (op *compiledOp) resolve(c *IntCodeComputer, positions []int) error {
+	for i, p := range op.params {
+		positions[i] = p.value
+		if p.mode == 2 {
+			positions[i] += c.relativeBase
+			if positions[i] < 0 {
+				return c.newFault(ErrNegativeAddress, "parameter %d resolves to %d", i+1, positions[i])
+			}
+			if positions[i] > MaxAddress {
+				return c.newFault(ErrAddress, "parameter %d resolves to %d", i+1, positions[i])
+			}
+		}
+	}
+	return nil
+}
+
+// begin resolves the parameters and counts the instruction, like the interpreter does before
+// executing it.
+func (op *compiledOp) begin(c *IntCodeComputer, positions []int) error {
+	if err := op.resolve(c, positions); err != nil {
+		return err
+	}
+	c.instructionPtr = c.ptr
+	c.instructions++
+	return nil
+}
+
+var compiledOps = map[int]func(c *IntCodeComputer, op *compiledOp) (Status, error){
+	1: func(c *IntCodeComputer, op *compiledOp) (Status, error) {
+		var p [3]int
+		if err := op.begin(c, p[:]); err != nil {
+			return Faulted, err
+		}
+		if err := c.add(p[2], p[0], p[1]); err != nil {
+			return Faulted, err
+		}
+		c.ptr += op.length
+		return Running, nil
+	},
+	2: func(c *IntCodeComputer, op *compiledOp) (Status, error) {
+		var p [3]int
+		if err := op.begin(c, p[:]); err != nil {
+			return Faulted, err
+		}
+		if err := c.mul(p[2], p[0], p[1]); err != nil {
+			return Faulted, err
+		}
+		c.ptr += op.length
+		return Running, nil
+	},
+	3: func(c *IntCodeComputer, op *compiledOp) (Status, error) {
+		if len(c.input) == 0 {
+			return NeedsInput, nil
+		}
+		var p [1]int
+		if err := op.begin(c, p[:]); err != nil {
+			return Faulted, err
+		}
+		if err := c.setText(p[0], c.input[0]); err != nil {
+			return Faulted, err
+		}
+		c.input = c.input[1:]
+		c.ptr += op.length
+		return Running, nil
+	},
+	4: func(c *IntCodeComputer, op *compiledOp) (Status, error) {
+		var p [1]int
+		if err := op.begin(c, p[:]); err != nil {
+			return Faulted, err
+		}
+		c.lastOut = c.getText(p[0])
+		c.output = c.getValue(p[0])
+		c.ptr += op.length
+		return ProducedOutput, nil
+	},
+	5: compiledJump(false),
+	6: compiledJump(true),
+	7: func(c *IntCodeComputer, op *compiledOp) (Status, error) {
+		var p [3]int
+		if err := op.begin(c, p[:]); err != nil {
+			return Faulted, err
+		}
+		if c.cmp(p[0], p[1]) < 0 {
+			c.setValue(p[2], 1)
+		} else {
+			c.setValue(p[2], 0)
+		}
+		c.ptr += op.length
+		return Running, nil
+	},
+	8: func(c *IntCodeComputer, op *compiledOp) (Status, error) {
+		var p [3]int
+		if err := op.begin(c, p[:]); err != nil {
+			return Faulted, err
+		}
+		if c.cmp(p[0], p[1]) == 0 {
+			c.setValue(p[2], 1)
+		} else {
+			c.setValue(p[2], 0)
+		}
+		c.ptr += op.length
+		return Running, nil
+	},
+	9: func(c *IntCodeComputer, op *compiledOp) (Status, error) {
+		var p [1]int
+		if err := op.begin(c, p[:]); err != nil {
+			return Faulted, err
+		}
+		val, err := c.getAddress(p[0])
+		if err != nil {
+			return Faulted, err
+		}
+		c.relativeBase += val
+		c.ptr += op.length
+		return Running, nil
+	},
+	99: func(c *IntCodeComputer, op *compiledOp) (Status, error) {
+		return Halted, nil
+	},
+}
+
+// compiledJump returns jump if true, or jump if false when zero is set.
+func compiledJump(zero bool) func(c *IntCodeComputer, op *compiledOp) (Status, error) {
+	return func(c *IntCodeComputer, op *compiledOp) (Status, error) {
+		var p [2]int
+		if err := op.begin(c, p[:]); err != nil {
+			return Faulted, err
+		}
+		if c.isZero(p[0]) == zero {
+			pos, err := c.getAddress(p[1])
+			if err != nil {
+				return Faulted, err
+			}
+			c.ptr = pos
+			return Running, nil
+		}
+		c.ptr += op.length
+		return Running, nil
+	}
+}
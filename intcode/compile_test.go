@@ -0,0 +1,127 @@
+package intcode
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// runEngine runs program with the step API and returns everything that should be the same whichever
+// engine runs it.
+func runEngine(program string, compiled bool, inputs ...int64) ([]int64, Status, error, Registers) {
+	c := NewIntCodeComputer(strings.Split(program, ","), nil, nil, nil, false, nil)
+	c.SetCompiled(compiled)
+	c.SetInstructionBudget(100000)
+	c.ProvideInput(inputs...)
+	status, err := c.RunUntilInputNeeded()
+	return c.TakeOutputs(), status, err, c.Registers()
+}
+
+func Test_CompiledMatchesInterpreter(t *testing.T) {
+	table := []struct {
+		program string
+		inputs  []int64
+	}{
+		{`109,1,204,-1,1001,100,1,100,1008,100,16,101,1006,101,0,99`, nil},
+		{`1102,34915192,34915192,7,4,7,99,0`, nil},
+		{`3,9,8,9,10,9,4,9,99,-1,8`, []int64{8}},
+		{`3,3,1107,-1,8,3,4,3,99`, []int64{5}},
+		{`3,12,6,12,15,1,13,14,13,4,13,99,-1,0,1,9`, []int64{0}},
+		{`3,21,1008,21,8,20,1005,20,22,107,8,21,20,1006,20,31,1106,0,36,98,0,0,1002,21,125,20,4,20,1105,1,46,104,999,1105,1,46,1101,1000,1,20,4,20,1105,1,46,98,99`, []int64{9}},
+		{`3,0,4,0,3,0,4,0,99`, []int64{1}},
+		// self modifying: the output's operand is incremented until it is 3
+		{`104,0,1001,1,1,1,1007,1,3,20,1005,20,0,99,0,0,0,0,0,0,0`, nil},
+		// self modifying: the add at 0 rewrites itself into a halt
+		{`1101,98,1,0,1105,1,0`, nil},
+		{`1,7,8,9,4,9,99,99999999999999999999,1,0`, nil},
+		{`1,0,0,0,42`, nil},
+		{`301,0,0,0,99`, nil},
+		{`1,-1,0,0,99`, nil},
+		{`109,-5,2201,0,0,0,99`, nil},
+		{`11101,1,1,3,99`, nil},
+		{`1106,0,-3,99`, nil},
+		{`1105,1,0`, nil},
+		{`1102,4611686018427387904,2,5,99,0`, nil},
+		{`1101,1,1,9223372036854775807,99`, nil},
+		{`109,9223372036854775807,21101,1,1,0,99`, nil},
+		{`1101,0,0,20,5,4,9,99,0,99999999999999999999`, nil},
+		{`1101,0,0,20,9,7,99,99999999999999999999`, nil},
+		// code far away in sparse memory is interpreted
+		{`1101,99,0,2000000,1105,1,2000000`, nil},
+	}
+
+	for _, test := range table {
+		outputs, status, err, regs := runEngine(test.program, false, test.inputs...)
+		cOutputs, cStatus, cErr, cRegs := runEngine(test.program, true, test.inputs...)
+		assert.Equal(t, outputs, cOutputs, test.program)
+		assert.Equal(t, status, cStatus, test.program)
+		assert.Equal(t, err, cErr, test.program)
+		assert.Equal(t, regs, cRegs, test.program)
+	}
+
+	// an input too wide for 64 bits faults without being counted
+	for _, compiled := range []bool{false, true} {
+		c := NewIntCodeComputer(strings.Split(`1101,0,0,20,3,0,99`, ","), nil, nil, nil, false, nil)
+		c.SetCompiled(compiled)
+		c.ProvideInputText("100000000000000000000")
+		_, err := c.RunUntilInputNeeded()
+		assert.True(t, errors.Is(err, ErrOverflow), "%v", err)
+		assert.Equal(t, uint64(1), c.Instructions())
+	}
+}
+
+func Test_CompiledInvalidation(t *testing.T) {
+	c := NewIntCodeComputer(strings.Split(`104,0,1001,1,1,1,1007,1,3,20,1005,20,0,99,0,0,0,0,0,0,0`, ","), nil, nil, nil, false, nil)
+	c.SetCompiled(true)
+	_, err := c.RunUntilInputNeeded()
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{0, 1, 2}, c.TakeOutputs())
+
+	code := c.memory.code
+	assert.Nil(t, code.lookup(0))
+	assert.NotNil(t, code.lookup(2))
+	assert.NotNil(t, code.lookup(13))
+
+	// writes from outside the program invalidate too
+	c.Poke(4, 2)
+	assert.Nil(t, code.lookup(2))
+	assert.NotNil(t, code.lookup(6))
+
+	// instructions compiled from the original program are kept by Reset
+	c.Reset()
+	code = c.memory.code
+	assert.NotNil(t, code.lookup(0))
+	assert.NotNil(t, code.lookup(2))
+	assert.NotNil(t, code.lookup(6))
+	_, err = c.RunUntilInputNeeded()
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{0, 1, 2}, c.TakeOutputs())
+
+	c.Reset()
+	c.SetCompiled(false)
+	_, err = c.RunUntilInputNeeded()
+	assert.Nil(t, err)
+	assert.Nil(t, c.memory.code)
+}
+
+func Test_CompiledCodeSharing(t *testing.T) {
+	c := NewIntCodeComputer(strings.Split(`104,0,1001,1,1,1,1007,1,3,20,1005,20,0,99,0,0,0,0,0,0,0`, ","), nil, nil, nil, false, nil)
+	c.SetCompiled(true)
+	c.RunUntilInputNeeded()
+
+	// clones share the compiled pages until one of them changes its code
+	clone := c.Clone()
+	assert.True(t, clone.memory.code.pages[0] == c.memory.code.pages[0])
+	clone.Poke(6, 8)
+	assert.Nil(t, clone.memory.code.lookup(6))
+	assert.NotNil(t, c.memory.code.lookup(6))
+	assert.False(t, clone.memory.code.pages[0] == c.memory.code.pages[0])
+
+	// so does the memory after a Reset
+	c.Reset()
+	assert.True(t, c.memory.code.pages[0] == c.origCode.pages[0])
+	c.RunUntilInputNeeded()
+	assert.Equal(t, []int64{0, 1, 2}, c.TakeOutputs())
+	assert.NotNil(t, c.origCode.lookup(2))
+}
@@ -0,0 +1,139 @@
+package intcode
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// conformanceCases are the examples published with the puzzles that define the computer: day2's
+// memory results, day5's modes and comparisons, and day9's relative mode and large numbers.
+var conformanceCases = []struct {
+	name    string
+	program string
+	inputs  []int64
+	outputs []string
+	memory  string // the expected memory after halting, if checked
+}{
+	{name: "day2 example", program: `1,9,10,3,2,3,11,0,99,30,40,50`, memory: `3500,9,10,70,2,3,11,0,99,30,40,50`},
+	{name: "day2 add", program: `1,0,0,0,99`, memory: `2,0,0,0,99`},
+	{name: "day2 multiply", program: `2,3,0,3,99`, memory: `2,3,0,6,99`},
+	{name: "day2 multiply past the end", program: `2,4,4,5,99,0`, memory: `2,4,4,5,99,9801`},
+	{name: "day2 self modifying", program: `1,1,1,4,99,5,6,0,99`, memory: `30,1,1,4,2,5,6,0,99`},
+	{name: "day5 echo", program: `3,0,4,0,99`, inputs: []int64{-42}, outputs: []string{"-42"}},
+	{name: "day5 immediate mode", program: `1002,4,3,4,33`, memory: `1002,4,3,4,99`},
+	{name: "day5 negative immediate", program: `1101,100,-1,4,0`, memory: `1101,100,-1,4,99`},
+	{name: "day5 equal to 8 position", program: `3,9,8,9,10,9,4,9,99,-1,8`, inputs: []int64{8}, outputs: []string{"1"}},
+	{name: "day5 not equal to 8 position", program: `3,9,8,9,10,9,4,9,99,-1,8`, inputs: []int64{7}, outputs: []string{"0"}},
+	{name: "day5 less than 8 position", program: `3,9,7,9,10,9,4,9,99,-1,8`, inputs: []int64{5}, outputs: []string{"1"}},
+	{name: "day5 not less than 8 position", program: `3,9,7,9,10,9,4,9,99,-1,8`, inputs: []int64{8}, outputs: []string{"0"}},
+	{name: "day5 equal to 8 immediate", program: `3,3,1108,-1,8,3,4,3,99`, inputs: []int64{8}, outputs: []string{"1"}},
+	{name: "day5 not equal to 8 immediate", program: `3,3,1108,-1,8,3,4,3,99`, inputs: []int64{9}, outputs: []string{"0"}},
+	{name: "day5 less than 8 immediate", program: `3,3,1107,-1,8,3,4,3,99`, inputs: []int64{-3}, outputs: []string{"1"}},
+	{name: "day5 not less than 8 immediate", program: `3,3,1107,-1,8,3,4,3,99`, inputs: []int64{10}, outputs: []string{"0"}},
+	{name: "day5 jump position zero", program: `3,12,6,12,15,1,13,14,13,4,13,99,-1,0,1,9`, inputs: []int64{0}, outputs: []string{"0"}},
+	{name: "day5 jump position non zero", program: `3,12,6,12,15,1,13,14,13,4,13,99,-1,0,1,9`, inputs: []int64{3}, outputs: []string{"1"}},
+	{name: "day5 jump immediate zero", program: `3,3,1105,-1,9,1101,0,0,12,4,12,99,1`, inputs: []int64{0}, outputs: []string{"0"}},
+	{name: "day5 jump immediate non zero", program: `3,3,1105,-1,9,1101,0,0,12,4,12,99,1`, inputs: []int64{-1}, outputs: []string{"1"}},
+	{name: "day5 below 8", program: day5Compare, inputs: []int64{7}, outputs: []string{"999"}},
+	{name: "day5 8", program: day5Compare, inputs: []int64{8}, outputs: []string{"1000"}},
+	{name: "day5 above 8", program: day5Compare, inputs: []int64{9}, outputs: []string{"1001"}},
+	{name: "day9 quine", program: day9Quine, outputs: strings.Split(day9Quine, ",")},
+	{name: "day9 16 digit output", program: `1102,34915192,34915192,7,4,7,99,0`, outputs: []string{"1219070632396864"}},
+	{name: "day9 large number", program: `104,1125899906842624,99`, outputs: []string{"1125899906842624"}},
+	{name: "day9 relative base", program: `109,19,204,-19,99`, outputs: []string{"109"}},
+	{name: "day9 relative input", program: `109,10,203,0,204,0,99`, inputs: []int64{77}, outputs: []string{"77"}},
+	{name: "day9 relative base accumulates", program: `109,1,109,2,21101,3,4,0,204,0,99`, outputs: []string{"7"}},
+	{name: "day9 memory past the program", program: `1101,5,6,1000,4,1000,99`, outputs: []string{"11"}},
+}
+
+const day5Compare = `3,21,1008,21,8,20,1005,20,22,107,8,21,20,1006,20,31,1106,0,36,98,0,0,1002,21,125,20,4,20,1105,1,46,104,999,1105,1,46,1101,1000,1,20,4,20,1105,1,46,98,99`
+
+const day9Quine = `109,1,204,-1,1001,100,1,100,1008,100,16,101,1006,101,0,99`
+
+// conformanceEngines run a program to completion in each of the ways the computer can be driven, and
+// return its outputs and final memory.
+var conformanceEngines = []struct {
+	name string
+	run  func(program []string, inputs []int64) ([]string, []string, error)
+}{
+	{"channels", func(program []string, inputs []int64) ([]string, []string, error) {
+		in := make(chan string, len(inputs))
+		out := make(chan string, 100)
+		for _, v := range inputs {
+			in <- strconv.FormatInt(v, 10)
+		}
+		c := NewIntCodeComputer(program, in, out, nil, false, nil)
+		err := c.Execute()
+		close(out)
+		var outputs []string
+		for o := range out {
+			outputs = append(outputs, o)
+		}
+		return outputs, programOf(c), err
+	}},
+	{"step", func(program []string, inputs []int64) ([]string, []string, error) {
+		c := NewIntCodeComputer(program, nil, nil, nil, false, nil)
+		c.ProvideInput(inputs...)
+		var outputs []string
+		for {
+			status, err := c.RunUntilOutput()
+			if status != ProducedOutput {
+				return outputs, programOf(c), err
+			}
+			outputs = append(outputs, c.OutputText())
+		}
+	}},
+	{"compiled", func(program []string, inputs []int64) ([]string, []string, error) {
+		c := NewIntCodeComputer(program, nil, nil, nil, false, nil)
+		c.SetCompiled(true)
+		c.ProvideInput(inputs...)
+		var outputs []string
+		for {
+			status, err := c.RunUntilOutput()
+			if status != ProducedOutput {
+				return outputs, programOf(c), err
+			}
+			outputs = append(outputs, c.OutputText())
+		}
+	}},
+	{"devices", func(program []string, inputs []int64) ([]string, []string, error) {
+		out := new(SliceOutput)
+		c := NewIntCodeComputerWithDevices(program, NewSliceInput(inputs...), out)
+		err := c.ExecuteContext(context.Background())
+		return out.Values, programOf(c), err
+	}},
+	{"clone", func(program []string, inputs []int64) ([]string, []string, error) {
+		c := NewIntCodeComputer(program, nil, nil, nil, false, nil).Clone()
+		out := new(SliceOutput)
+		c.SetDevices(NewSliceInput(inputs...), out)
+		err := c.Execute()
+		return out.Values, programOf(c), err
+	}},
+	{"hooked", func(program []string, inputs []int64) ([]string, []string, error) {
+		c := NewIntCodeComputer(program, nil, nil, nil, false, nil)
+		NewProfiler(c)
+		out := new(SliceOutput)
+		c.SetDevices(NewSliceInput(inputs...), out)
+		err := c.Execute()
+		return out.Values, programOf(c), err
+	}},
+}
+
+func Test_Conformance(t *testing.T) {
+	for _, engine := range conformanceEngines {
+		for _, test := range conformanceCases {
+			outputs, memory, err := engine.run(strings.Split(test.program, ","), test.inputs)
+			name := engine.name + ": " + test.name
+			if !assert.Nil(t, err, name) {
+				continue
+			}
+			assert.Equal(t, test.outputs, outputs, name)
+			if test.memory != "" {
+				assert.Equal(t, test.memory, strings.Join(memory, ","), name)
+			}
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package intcode
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Debugger pauses a computer on breakpoints and watchpoints.  It works both when the host drives the
+// computer through Continue and Step, and when the computer runs with Execute, which returns
+// ErrPaused when a breakpoint is hit.
+type Debugger struct {
+	c          *IntCodeComputer
+	hooks      *Hooks
+	addrs      map[int]bool
+	opCodes    map[int]bool
+	watches    map[int]bool
+	resumeFrom int
+	pending    []string
+	reason     string
+	symbols    map[string]int
+}
+
+func NewDebugger(c *IntCodeComputer) *Debugger {
+	d := &Debugger{
+		c:          c,
+		addrs:      make(map[int]bool),
+		opCodes:    make(map[int]bool),
+		watches:    make(map[int]bool),
+		resumeFrom: -1,
+	}
+	d.hooks = &Hooks{
+		BeforeInstruction: d.beforeInstruction,
+		AfterWrite:        d.afterWrite,
+	}
+	c.AddHooks(d.hooks)
+	return d
+}
+
+// Detach removes the debugger's hooks from the computer.
+func (d *Debugger) Detach() {
+	d.c.RemoveHooks(d.hooks)
+}
+
+func (d *Debugger) Computer() *IntCodeComputer {
+	return d.c
+}
+
+func (d *Debugger) BreakAt(addr int) {
+	d.addrs[addr] = true
+}
+
+func (d *Debugger) BreakOnOpCode(opCode int) {
+	d.opCodes[opCode] = true
+}
+
+// Watch pauses the computer after an instruction writes to addr.
+func (d *Debugger) Watch(addr int) {
+	d.watches[addr] = true
+}
+
+func (d *Debugger) ClearBreakAt(addr int) {
+	delete(d.addrs, addr)
+}
+
+func (d *Debugger) ClearBreakOnOpCode(opCode int) {
+	delete(d.opCodes, opCode)
+}
+
+func (d *Debugger) ClearWatch(addr int) {
+	delete(d.watches, addr)
+}
+
+// SetSymbols sets the labels used to describe addresses, such as the ones returned by Assemble.
+func (d *Debugger) SetSymbols(symbols map[string]int) {
+	d.symbols = symbols
+}
+
+// Symbol returns the address of a label.
+func (d *Debugger) Symbol(name string) (int, bool) {
+	addr, ok := d.symbols[name]
+	return addr, ok
+}
+
+// Describe formats addr with its label, if it has one.
+func (d *Debugger) Describe(addr int) string {
+	if name, ok := symbolAt(d.symbols, addr); ok {
+		return fmt.Sprintf("%d (%s)", addr, name)
+	}
+	return strconv.Itoa(addr)
+}
+
+// Reason describes why the computer last paused.
+func (d *Debugger) Reason() string {
+	return d.reason
+}
+
+func (d *Debugger) beforeInstruction(c *IntCodeComputer, ptr int, instruction int) bool {
+	if ptr == d.resumeFrom {
+		d.resumeFrom = -1
+		return true
+	}
+	d.resumeFrom = -1
+
+	var reasons []string
+	if len(d.pending) > 0 {
+		reasons = append(reasons, d.pending...)
+		d.pending = nil
+	}
+	if d.addrs[ptr] {
+		reasons = append(reasons, "breakpoint at "+d.Describe(ptr))
+	}
+	if d.opCodes[instruction%100] {
+		reasons = append(reasons, fmt.Sprintf("opcode %d at %s", instruction%100, d.Describe(ptr)))
+	}
+	if len(reasons) == 0 {
+		return true
+	}
+	d.reason = strings.Join(reasons, ", ")
+	// let the instruction run when execution is resumed
+	d.resumeFrom = ptr
+	return false
+}
+
+func (d *Debugger) afterWrite(c *IntCodeComputer, addr int, old, new int64) {
+	if d.watches[addr] {
+		d.pending = append(d.pending, fmt.Sprintf("write to %s: %d -> %d", d.Describe(addr), old, new))
+	}
+}
+
+// Step executes a single instruction, ignoring breakpoints.
+func (d *Debugger) Step() (Status, error) {
+	d.resumeFrom = d.c.ptr
+	status, err := d.c.Step()
+	if status == Paused {
+		// a breakpoint belonging to another hook, not this debugger
+		d.resumeFrom = -1
+	}
+	return status, err
+}
+
+// Continue runs until a breakpoint or watchpoint pauses the computer, or until it produces output,
+// needs input, halts or faults.
+func (d *Debugger) Continue() (Status, error) {
+	d.reason = ""
+	for {
+		if d.c.budget > 0 && d.c.instructions >= d.c.budget {
+			d.c.err = ErrBudgetExhausted
+			return Faulted, d.c.err
+		}
+		status, err := d.c.Step()
+		if status != Running && status != Yielded {
+			return status, err
+		}
+	}
+}
+
+// Dump formats the memory cells from start up to, but not including, end.
+func (d *Debugger) Dump(start, end int) string {
+	var sb strings.Builder
+	for addr := start; addr < end; addr++ {
+		if (addr-start)%8 == 0 {
+			if addr > start {
+				sb.WriteByte('\n')
+			}
+			sb.WriteString(fmt.Sprintf("%6d:", addr))
+		}
+		sb.WriteString(" ")
+		sb.WriteString(d.c.PeekText(addr))
+	}
+	return sb.String()
+}
+
+// Breakpoints lists the breakpoints and watchpoints that are set.
+func (d *Debugger) Breakpoints() string {
+	var lines []string
+	for _, a := range sortedKeys(d.addrs) {
+		lines = append(lines, "break at "+d.Describe(a))
+	}
+	for _, o := range sortedKeys(d.opCodes) {
+		lines = append(lines, fmt.Sprintf("break on opcode %d", o))
+	}
+	for _, a := range sortedKeys(d.watches) {
+		lines = append(lines, "watch "+d.Describe(a))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func sortedKeys(m map[int]bool) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
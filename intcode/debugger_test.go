@@ -0,0 +1,84 @@
+package intcode
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_DebuggerBreakpoints(t *testing.T) {
+	// echoes every input doubled, forever
+	c := NewIntCodeComputer(strings.Split(`3,11,1002,11,2,11,4,11,1105,1,0,0`, ","), nil, nil, nil, false, nil)
+	d := NewDebugger(c)
+	d.BreakAt(2)
+	d.Watch(11)
+
+	c.ProvideInput(5)
+	status, err := d.Continue()
+	assert.Nil(t, err)
+	assert.Equal(t, Paused, status)
+	assert.Equal(t, "write to 11: 0 -> 5, breakpoint at 2", d.Reason())
+	assert.Equal(t, 2, c.Registers().Ptr)
+
+	status, _ = d.Continue()
+	assert.Equal(t, Paused, status)
+	assert.Equal(t, "write to 11: 5 -> 10", d.Reason())
+	assert.Equal(t, 6, c.Registers().Ptr)
+
+	d.ClearWatch(11)
+	d.BreakOnOpCode(5)
+	status, _ = d.Continue()
+	assert.Equal(t, ProducedOutput, status)
+	assert.Equal(t, int64(10), c.Output())
+	status, _ = d.Continue()
+	assert.Equal(t, Paused, status)
+	assert.Equal(t, "opcode 5 at 8", d.Reason())
+
+	status, _ = d.Step()
+	assert.Equal(t, Running, status)
+	assert.Equal(t, 0, c.Registers().Ptr)
+
+	assert.Equal(t, "     8: 1105 1 0 10", d.Dump(8, 12))
+	assert.Equal(t, "break at 2\nbreak on opcode 5", d.Breakpoints())
+
+	d.Detach()
+	c.ProvideInput(1)
+	status, _ = d.Continue()
+	assert.Equal(t, ProducedOutput, status)
+}
+
+func Test_DebuggerExecute(t *testing.T) {
+	quit := make(chan string, 1)
+	c := NewIntCodeComputer(strings.Split(`1101,1,2,5,104,0,99`, ","), nil, make(chan string, 1), quit, false, nil)
+	d := NewDebugger(c)
+	d.BreakAt(4)
+
+	assert.Equal(t, ErrPaused, c.Execute())
+	<-quit
+	assert.Equal(t, int64(3), c.Peek(5))
+	c.Poke(5, 7)
+
+	assert.Nil(t, c.Execute())
+	assert.Equal(t, "7", <-quit)
+}
+
+func Test_DebuggerBreakOnInput(t *testing.T) {
+	in := make(chan string, 1)
+	quit := make(chan string, 1)
+	c := NewIntCodeComputer(strings.Split(`3,0,4,0,99`, ","), in, make(chan string, 1), quit, false, nil)
+	d := NewDebugger(c)
+	d.BreakAt(0)
+	in <- "7"
+
+	// the input instruction needs input before it can run, which mustn't hit the breakpoint twice
+	pauses := 0
+	err := c.Execute()
+	for err == ErrPaused {
+		<-quit
+		pauses++
+		err = c.Execute()
+	}
+	assert.Nil(t, err)
+	assert.Equal(t, "7", <-quit)
+	assert.Equal(t, 1, pauses)
+}
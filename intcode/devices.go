@@ -0,0 +1,301 @@
+package intcode
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ErrNoInput is returned by input devices that have run out of values.
+var ErrNoInput = errors.New("no input left")
+
+// InputDevice supplies the values read by input instructions, in the string program format.  Input may
+// block, but should return ctx.Err() when ctx is cancelled.  An error stops the computer, which returns it
+// from ExecuteContext; executing again retries the input instruction.
+type InputDevice interface {
+	Input(ctx context.Context) (string, error)
+}
+
+// OutputDevice receives the values written by output instructions, in the string program format.  Output
+// may block, but should return ctx.Err() when ctx is cancelled.  An error stops the computer, which
+// returns it from ExecuteContext; the value counts as not delivered, so executing again delivers it again
+// before running on.
+type OutputDevice interface {
+	Output(ctx context.Context, value string) error
+}
+
+// NewIntCodeComputerWithDevices creates a computer for program that reads its input from in and writes
+// its output to out when executed.  Either may be nil if the program doesn't use it.
+func NewIntCodeComputerWithDevices(program []string, in InputDevice, out OutputDevice) *IntCodeComputer {
+	c := NewIntCodeComputer(program, nil, nil, nil, false, nil)
+	c.SetDevices(in, out)
+	return c
+}
+
+// SetDevices replaces the devices the computer reads from and writes to when executed, including the
+// channels it was created with.  It must not be called while the computer is executing.
+func (c *IntCodeComputer) SetDevices(in InputDevice, out OutputDevice) {
+	if in == nil {
+		in = new(SliceInput)
+	}
+	if out == nil {
+		out = discardOutput{}
+	}
+	c.inDev = in
+	c.outDev = out
+}
+
+// channelInput is the input device for computers created with channels.  It sends the input prompt, if
+// there is one, before waiting for a value.
+type channelInput struct {
+	c *IntCodeComputer
+}
+
+func (d channelInput) Input(ctx context.Context) (string, error) {
+	c := d.c
+	if c.inputPrompt != nil {
+		select {
+		case c.prompt <- *(c.inputPrompt):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	select {
+	case value := <-c.in:
+		return value, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// channelOutput is the output device for computers created with channels.  If pauseOnOutput is set it
+// waits for OutputProcessed after sending each value.
+type channelOutput struct {
+	c *IntCodeComputer
+}
+
+// delivered wraps an error from an output device that happened after the value was delivered, so it
+// isn't output again.
+type delivered struct {
+	err error
+}
+
+func (e *delivered) Error() string {
+	return e.err.Error()
+}
+
+func (d channelOutput) Output(ctx context.Context, value string) error {
+	c := d.c
+	select {
+	case c.out <- value:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if c.pauseOnOutput {
+		select {
+		case <-c.processed:
+		case <-ctx.Done():
+			return &delivered{ctx.Err()}
+		}
+	}
+	return nil
+}
+
+type discardOutput struct{}
+
+func (discardOutput) Output(ctx context.Context, value string) error {
+	return nil
+}
+
+// SliceInput is an input device that supplies its values in order, and then ErrNoInput.
+type SliceInput []string
+
+// NewSliceInput returns an input device that supplies values in order.
+func NewSliceInput(values ...int64) *SliceInput {
+	s := make(SliceInput, len(values))
+	for i, v := range values {
+		s[i] = strconv.FormatInt(v, 10)
+	}
+	return &s
+}
+
+func (s *SliceInput) Input(ctx context.Context) (string, error) {
+	if s == nil || len(*s) == 0 {
+		return "", ErrNoInput
+	}
+	value := (*s)[0]
+	*s = (*s)[1:]
+	return value, nil
+}
+
+// Append queues more values.
+func (s *SliceInput) Append(values ...int64) {
+	for _, v := range values {
+		*s = append(*s, strconv.FormatInt(v, 10))
+	}
+}
+
+// SliceOutput is an output device that collects the values it receives.
+type SliceOutput struct {
+	Values []string
+}
+
+func (s *SliceOutput) Output(ctx context.Context, value string) error {
+	s.Values = append(s.Values, value)
+	return nil
+}
+
+// Ints returns the collected values as int64.  Values that need arbitrary precision are returned as 0,
+// like Output does.
+func (s *SliceOutput) Ints() []int64 {
+	ints := make([]int64, len(s.Values))
+	for i, text := range s.Values {
+		ints[i], _, _ = parseCell(text)
+	}
+	return ints
+}
+
+// Last returns the last collected value, or "" if there are none.
+func (s *SliceOutput) Last() string {
+	if len(s.Values) == 0 {
+		return ""
+	}
+	return s.Values[len(s.Values)-1]
+}
+
+// ChanInput is an input device that receives values from a channel.  It returns ErrNoInput once the
+// channel is closed.
+type ChanInput <-chan string
+
+func (ch ChanInput) Input(ctx context.Context) (string, error) {
+	select {
+	case value, ok := <-ch:
+		if !ok {
+			return "", ErrNoInput
+		}
+		return value, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// ChanOutput is an output device that sends values on a channel.
+type ChanOutput chan<- string
+
+func (ch ChanOutput) Output(ctx context.Context, value string) error {
+	select {
+	case ch <- value:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// InputFunc is an input device that calls a function for each value.
+type InputFunc func() (int64, error)
+
+func (f InputFunc) Input(ctx context.Context) (string, error) {
+	v, err := f()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(v, 10), nil
+}
+
+// OutputFunc is an output device that calls a function with each value.  Values that need arbitrary
+// precision are passed as 0, like Output does.
+type OutputFunc func(value int64) error
+
+func (f OutputFunc) Output(ctx context.Context, value string) error {
+	v, _, err := parseCell(value)
+	if err != nil {
+		return err
+	}
+	return f(v)
+}
+
+// ASCIIInput is an input device that supplies the bytes read from a reader, such as a program's text
+// commands.  It returns io.EOF once the reader is exhausted.
+type ASCIIInput struct {
+	r *bufio.Reader
+}
+
+func NewASCIIInput(r io.Reader) *ASCIIInput {
+	return &ASCIIInput{r: bufio.NewReader(r)}
+}
+
+func (a *ASCIIInput) Input(ctx context.Context) (string, error) {
+	b, err := a.r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(int(b)), nil
+}
+
+// ASCIIOutput is an output device that writes ASCII values to a writer as text.  Values that aren't
+// ASCII, such as the answers the ASCII programs print at the end, are written as numbers on a line of
+// their own.
+type ASCIIOutput struct {
+	w io.Writer
+}
+
+func NewASCIIOutput(w io.Writer) *ASCIIOutput {
+	return &ASCIIOutput{w: w}
+}
+
+func (a *ASCIIOutput) Output(ctx context.Context, value string) error {
+	v, _, err := parseCell(value)
+	if err != nil {
+		return err
+	}
+	if v < 0 || v > 127 || len(value) > 3 {
+		_, err = fmt.Fprintf(a.w, "%s\n", value)
+		return err
+	}
+	_, err = a.w.Write([]byte{byte(v)})
+	return err
+}
+
+type teeInput struct {
+	in  InputDevice
+	log []OutputDevice
+}
+
+// TeeInput returns an input device that reads from in and also writes every value it reads to each of
+// the log devices, for example a SliceOutput recording the input.
+func TeeInput(in InputDevice, log ...OutputDevice) InputDevice {
+	return &teeInput{in: in, log: log}
+}
+
+func (t *teeInput) Input(ctx context.Context) (string, error) {
+	value, err := t.in.Input(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, d := range t.log {
+		if err := d.Output(ctx, value); err != nil {
+			return "", err
+		}
+	}
+	return value, nil
+}
+
+type teeOutput []OutputDevice
+
+// TeeOutput returns an output device that writes every value to each of outs in turn, stopping at the
+// first error.
+func TeeOutput(outs ...OutputDevice) OutputDevice {
+	return teeOutput(outs)
+}
+
+func (t teeOutput) Output(ctx context.Context, value string) error {
+	for _, d := range t {
+		if err := d.Output(ctx, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
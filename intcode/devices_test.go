@@ -0,0 +1,111 @@
+package intcode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// outputs the sum of pairs of inputs until it reads a 0
+const pairAdder = `3,20,1006,20,16,3,21,1,20,21,22,4,22,1105,1,0,99,0,0,0,0,0,0`
+
+func Test_Devices(t *testing.T) {
+	program := strings.Split(`3,11,3,12,1,11,12,13,4,13,99,0,0,0`, ",")
+
+	in := NewSliceInput(2, 3)
+	out := new(SliceOutput)
+	c := NewIntCodeComputerWithDevices(program, in, out)
+	assert.Nil(t, c.Execute())
+	assert.Equal(t, []string{"5"}, out.Values)
+	assert.Equal(t, []int64{5}, out.Ints())
+
+	// running out of input stops the computer, and it carries on once there is more
+	c.Reset()
+	in.Append(4)
+	assert.Equal(t, ErrNoInput, c.Execute())
+	in.Append(5)
+	assert.Nil(t, c.Execute())
+	assert.Equal(t, "9", out.Last())
+
+	// func devices
+	c.Reset()
+	n := int64(0)
+	var got []int64
+	c.SetDevices(InputFunc(func() (int64, error) {
+		n += 10
+		return n, nil
+	}), OutputFunc(func(v int64) error {
+		got = append(got, v)
+		return nil
+	}))
+	assert.Nil(t, c.Execute())
+	assert.Equal(t, []int64{30}, got)
+
+	// an output error leaves the output to be delivered again, without executing the output
+	// instruction again
+	c.Reset()
+	boom := errors.New("boom")
+	fail := true
+	c.SetDevices(NewSliceInput(1, 1), OutputFunc(func(v int64) error {
+		if fail {
+			return boom
+		}
+		got = append(got, v)
+		return nil
+	}))
+	p := NewProfiler(c)
+	assert.Equal(t, boom, c.Execute())
+	instructions := c.Instructions()
+	fail = false
+	assert.Nil(t, c.Execute())
+	assert.Equal(t, []int64{30, 2}, got)
+	assert.Equal(t, instructions, c.Instructions())
+	assert.Equal(t, uint64(1), p.Addresses[8])
+}
+
+func Test_ChanDevices(t *testing.T) {
+	in := make(chan string, 2)
+	out := make(chan string, 1)
+	c := NewIntCodeComputerWithDevices(strings.Split(`3,11,3,12,1,11,12,13,4,13,99,0,0,0`, ","), ChanInput(in), ChanOutput(out))
+
+	in <- "20"
+	in <- "22"
+	assert.Nil(t, c.Execute())
+	assert.Equal(t, "42", <-out)
+
+	c.Reset()
+	close(in)
+	assert.Equal(t, ErrNoInput, c.Execute())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Reset()
+	c.SetDevices(ChanInput(make(chan string)), nil)
+	assert.Equal(t, context.Canceled, c.ExecuteContext(ctx))
+}
+
+func Test_ASCIIDevices(t *testing.T) {
+	assembled, err := Assemble(asciiGreeter)
+	assert.Nil(t, err)
+
+	var out bytes.Buffer
+	c := NewIntCodeComputerWithDevices(assembled.Program, NewASCIIInput(strings.NewReader("Bob\n")), NewASCIIOutput(&out))
+	assert.Nil(t, c.Execute())
+	assert.Equal(t, "Name?\nHi Bob\n1003\n1125899906842624\n", out.String())
+}
+
+func Test_TeeDevices(t *testing.T) {
+	program := strings.Split(pairAdder, ",")
+
+	inputs, outputs, all := new(SliceOutput), new(SliceOutput), new(SliceOutput)
+	c := NewIntCodeComputerWithDevices(program,
+		TeeInput(NewSliceInput(1, 2, 3, 4, 0), inputs),
+		TeeOutput(outputs, all))
+	assert.Nil(t, c.Execute())
+	assert.Equal(t, []int64{1, 2, 3, 4, 0}, inputs.Ints())
+	assert.Equal(t, []int64{3, 7}, outputs.Ints())
+	assert.Equal(t, outputs.Values, all.Values)
+}
@@ -0,0 +1,436 @@
+package intcode
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var mnemonics = map[int]string{
+	1:  "ADD",
+	2:  "MUL",
+	3:  "IN",
+	4:  "OUT",
+	5:  "JNZ",
+	6:  "JZ",
+	7:  "LT",
+	8:  "EQ",
+	9:  "ARB",
+	99: "HLT",
+}
+
+// Param is a decoded instruction parameter.
+type Param struct {
+	Mode  int
+	Value string
+}
+
+func (p Param) String() string {
+	switch p.Mode {
+	case 1:
+		return "#" + p.Value
+	case 2:
+		if strings.HasPrefix(p.Value, "-") {
+			return "rb" + p.Value
+		}
+		return "rb+" + p.Value
+	}
+	return "[" + p.Value + "]"
+}
+
+// Instruction is a decoded instruction.
+type Instruction struct {
+	Addr     int
+	OpCode   int
+	Mnemonic string
+	Params   []Param
+	Length   int
+}
+
+func (i Instruction) String() string {
+	params := make([]string, len(i.Params))
+	for j, p := range i.Params {
+		params[j] = p.String()
+	}
+	return strings.TrimSpace(fmt.Sprintf("%-4s %s", i.Mnemonic, strings.Join(params, ", ")))
+}
+
+// Line is one line of a listing, either an instruction or a run of data cells.  Text is set when the
+// data is printable ASCII.
+type Line struct {
+	Addr        int
+	Instruction *Instruction
+	Data        []string
+	Text        string
+	Comment     string
+	Label       string
+}
+
+func (l Line) String() string {
+	var s string
+	switch {
+	case l.Instruction != nil:
+		s = l.Instruction.String()
+	case l.Text != "":
+		s = "DATA " + strconv.Quote(l.Text)
+	default:
+		s = "DATA " + strings.Join(l.Data, ", ")
+	}
+	s = fmt.Sprintf("%6d: %s", l.Addr, s)
+	if l.Comment != "" {
+		s = fmt.Sprintf("%-40s ; %s", s, l.Comment)
+	}
+	if l.Label != "" {
+		s = l.Label + ":\n" + s
+	}
+	return s
+}
+
+// Listing is a disassembled program.
+type Listing struct {
+	Lines []Line
+}
+
+func (l *Listing) String() string {
+	var sb strings.Builder
+	for _, line := range l.Lines {
+		sb.WriteString(line.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// minTextLength is the shortest run of printable cells shown as a string.
+const minTextLength = 4
+
+// dataCellsPerLine is how many numeric data cells are shown on a line.
+const dataCellsPerLine = 8
+
+// Disassemble decodes program into a listing.  Code is found by following the control flow from
+// address 0.  Jumps through memory can't be followed, so the address after an unconditional jump is
+// also treated as code when an instruction stores it at rb+0, which is how intcode programs push
+// return addresses.  Everything else is shown as data, with runs of printable cells that contain a
+// space or a newline shown as text.
+func Disassemble(program []string) (*Listing, error) {
+	return DisassembleWithSymbols(program, nil)
+}
+
+// DisassembleWithSymbols is like Disassemble, but labels the lines at the addresses of symbols, such as
+// the ones returned by Assemble.
+func DisassembleWithSymbols(program []string, symbols map[string]int) (*Listing, error) {
+	d, err := newDisassembler(program, symbols)
+	if err != nil {
+		return nil, err
+	}
+	return d.listing(), nil
+}
+
+// newDisassembler parses program and finds its code.
+func newDisassembler(program []string, symbols map[string]int) (*disassembler, error) {
+	mem, wide, err := parseProgram(program)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &disassembler{
+		mem:     mem,
+		wide:    wide,
+		symbols: symbols,
+		decoded: make(map[int]*Instruction),
+		owner:   make([]int, len(mem)),
+		jumps:   make(map[int][]int),
+		returns: make(map[int]bool),
+	}
+	for i := range d.owner {
+		d.owner[i] = -1
+	}
+	d.trace(0)
+
+	for {
+		var found []int
+		for _, inst := range d.decoded {
+			if v, ok := d.pushedReturn(inst); ok && d.returns[v] && d.decoded[v] == nil {
+				found = append(found, v)
+			}
+		}
+		if len(found) == 0 {
+			break
+		}
+		for _, addr := range found {
+			d.trace(addr)
+		}
+	}
+
+	return d, nil
+}
+
+// DisassembleAt decodes the instruction at addr in the computer's memory.
+func (c *IntCodeComputer) DisassembleAt(addr int) (Instruction, bool) {
+	if addr < 0 || addr >= c.memory.length {
+		return Instruction{}, false
+	}
+	d := disassembler{
+		mem:        make([]int64, 0, 4),
+		wide:       make(map[int]*big.Int),
+		extensions: c.extensions,
+	}
+	for i := 0; i < 4 && addr+i < c.memory.length; i++ {
+		d.mem = append(d.mem, c.getValue(addr+i))
+		if c.isWide(addr + i) {
+			d.wide[i] = c.getBig(addr + i)
+		}
+	}
+	inst, ok := d.decode(0)
+	inst.Addr = addr
+	return inst, ok
+}
+
+type disassembler struct {
+	mem     []int64
+	wide    map[int]*big.Int
+	symbols map[string]int
+	// extensions are decoded as well as the built in instructions, if set
+	extensions map[int]*Extension
+	decoded    map[int]*Instruction
+	// owner is the address of the instruction each cell belongs to, or -1 for data
+	owner []int
+	// jumps maps jump targets to the addresses of the jumps
+	jumps   map[int][]int
+	returns map[int]bool
+}
+
+func (d *disassembler) text(addr int) string {
+	if w, ok := d.wide[addr]; ok {
+		return w.String()
+	}
+	return strconv.FormatInt(d.mem[addr], 10)
+}
+
+func (d *disassembler) decode(addr int) (Instruction, bool) {
+	inst := Instruction{Addr: addr}
+	if _, ok := d.wide[addr]; ok || d.mem[addr] < 0 {
+		return inst, false
+	}
+	v := int(d.mem[addr])
+	inst.OpCode = v % 100
+	length, ok := opCodeLengths[inst.OpCode]
+	inst.Mnemonic = mnemonics[inst.OpCode]
+	x := d.extensions[inst.OpCode]
+	if !ok && x != nil {
+		length, ok = x.Params+1, true
+		inst.Mnemonic = x.name()
+	}
+	if !ok {
+		return inst, false
+	}
+	if length == 0 {
+		length = 1
+	}
+	inst.Length = length
+	if addr+length > len(d.mem) {
+		return inst, false
+	}
+
+	modes := v / 100
+	for j := 1; j < length; j++ {
+		mode := modes % 10
+		modes /= 10
+		if mode > 2 {
+			return inst, false
+		}
+		if w, ok := opCodeWriteParams[inst.OpCode]; ok && w == j-1 && mode == 1 {
+			return inst, false
+		}
+		if x != nil && mode == 1 {
+			for _, w := range x.Writes {
+				if w == j-1 {
+					return inst, false
+				}
+			}
+		}
+		inst.Params = append(inst.Params, Param{Mode: mode, Value: d.text(addr + j)})
+	}
+	// a mode for a parameter the instruction doesn't have means this isn't code
+	return inst, modes == 0
+}
+
+func (d *disassembler) immediate(inst *Instruction, i int) (int, bool) {
+	if inst.Params[i].Mode != 1 {
+		return 0, false
+	}
+	v, err := strconv.Atoi(inst.Params[i].Value)
+	return v, err == nil
+}
+
+// pushedReturn returns the immediate value inst stores at rb+0, if any.
+func (d *disassembler) pushedReturn(inst *Instruction) (int, bool) {
+	if inst.OpCode != 1 && inst.OpCode != 2 {
+		return 0, false
+	}
+	if dst := inst.Params[2]; dst.Mode != 2 || dst.Value != "0" {
+		return 0, false
+	}
+	a, aok := d.immediate(inst, 0)
+	b, bok := d.immediate(inst, 1)
+	if !aok || !bok {
+		return 0, false
+	}
+	if inst.OpCode == 1 {
+		return a + b, true
+	}
+	return a * b, true
+}
+
+func (d *disassembler) trace(addr int) {
+	queue := []int{addr}
+	for len(queue) > 0 {
+		addr := queue[0]
+		queue = queue[1:]
+
+		for addr >= 0 && addr < len(d.mem) && d.owner[addr] == -1 {
+			inst, ok := d.decode(addr)
+			if !ok {
+				break
+			}
+			free := true
+			for i := addr; i < addr+inst.Length; i++ {
+				if d.owner[i] != -1 {
+					free = false
+				}
+			}
+			if !free {
+				break
+			}
+			for i := addr; i < addr+inst.Length; i++ {
+				d.owner[i] = addr
+			}
+			d.decoded[addr] = &inst
+
+			next := addr + inst.Length
+			if inst.OpCode == 99 {
+				break
+			}
+			if inst.OpCode == 5 || inst.OpCode == 6 {
+				if target, ok := d.immediate(&inst, 1); ok {
+					d.jumps[target] = append(d.jumps[target], addr)
+					queue = append(queue, target)
+				}
+				if cond, ok := d.immediate(&inst, 0); ok && (cond != 0) == (inst.OpCode == 5) {
+					d.returns[next] = true
+					break
+				}
+			}
+			addr = next
+		}
+	}
+}
+
+func (d *disassembler) listing() *Listing {
+	labelled := make(map[int]bool)
+	for _, addr := range d.symbols {
+		labelled[addr] = true
+	}
+
+	listing := new(Listing)
+	for addr := 0; addr < len(d.mem); {
+		if inst, ok := d.decoded[addr]; ok && d.owner[addr] == addr {
+			line := Line{Addr: addr, Instruction: inst, Comment: d.comment(inst)}
+			listing.Lines = append(listing.Lines, line)
+			addr += inst.Length
+			continue
+		}
+		// split data at labels so each label starts a line
+		end := addr + 1
+		for end < len(d.mem) && d.owner[end] == -1 && !labelled[end] {
+			end++
+		}
+		listing.Lines = append(listing.Lines, d.data(addr, end)...)
+		addr = end
+	}
+
+	for i := range listing.Lines {
+		listing.Lines[i].Label, _ = symbolAt(d.symbols, listing.Lines[i].Addr)
+	}
+	return listing
+}
+
+func (d *disassembler) comment(inst *Instruction) string {
+	var comments []string
+	if from, ok := d.jumps[inst.Addr]; ok {
+		sort.Ints(from)
+		s := make([]string, len(from))
+		for i, f := range from {
+			s[i] = strconv.Itoa(f)
+		}
+		comments = append(comments, "from "+strings.Join(s, ", "))
+	}
+	if inst.OpCode == 4 || inst.OpCode == 7 || inst.OpCode == 8 {
+		for i := range inst.Params {
+			if v, ok := d.immediate(inst, i); ok && printable(int64(v)) && v != '\n' {
+				comments = append(comments, strconv.QuoteRune(rune(v)))
+			}
+		}
+	}
+	return strings.Join(comments, " ")
+}
+
+// words reports whether the cells from start up to end look like text rather than numbers that happen
+// to be printable.
+func (d *disassembler) words(start, end int) bool {
+	for i := start; i < end; i++ {
+		if d.mem[i] == ' ' || d.mem[i] == '\n' {
+			return true
+		}
+	}
+	return false
+}
+
+func printable(v int64) bool {
+	return v == '\n' || (v >= 32 && v < 127)
+}
+
+// data splits the data cells from start up to end into lines, showing runs of printable cells as text.
+func (d *disassembler) data(start, end int) []Line {
+	var lines []Line
+	var cells []string
+	cellsStart := start
+
+	flush := func(addr int) {
+		for i := 0; i < len(cells); i += dataCellsPerLine {
+			j := i + dataCellsPerLine
+			if j > len(cells) {
+				j = len(cells)
+			}
+			lines = append(lines, Line{Addr: cellsStart + i, Data: cells[i:j]})
+		}
+		cells = nil
+		cellsStart = addr
+	}
+
+	for addr := start; addr < end; {
+		run := addr
+		for run < end && d.wide[run] == nil && printable(d.mem[run]) {
+			run++
+		}
+		if run-addr >= minTextLength && d.words(addr, run) {
+			flush(addr)
+			var sb strings.Builder
+			for i := addr; i < run; i++ {
+				sb.WriteRune(rune(d.mem[i]))
+			}
+			lines = append(lines, Line{Addr: addr, Text: sb.String(), Comment: fmt.Sprintf("%d cells", run-addr)})
+			addr = run
+			cellsStart = addr
+			continue
+		}
+		if len(cells) == 0 {
+			cellsStart = addr
+		}
+		cells = append(cells, d.text(addr))
+		addr++
+	}
+	flush(end)
+	return lines
+}
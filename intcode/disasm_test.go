@@ -0,0 +1,35 @@
+package intcode
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_Disassemble(t *testing.T) {
+	program := strings.Split(`21101,0,7,0,1105,1,10,104,10,99,109,1,2105,1,-1,72,105,32,116,104,101,114,101,10,-5,1,2`, ",")
+
+	listing, err := Disassemble(program)
+	assert.Nil(t, err)
+	assert.Equal(t, `     0: ADD  #0, #7, rb+0
+     4: JNZ  #1, #10
+     7: OUT  #10
+     9: HLT
+    10: ARB  #1                          ; from 4
+    12: JNZ  #1, rb-1
+    15: DATA "Hi there\n"                ; 9 cells
+    24: DATA -5, 1, 2
+`, listing.String())
+
+	_, err = Disassemble([]string{"1", "x"})
+	assert.NotNil(t, err)
+}
+
+func Test_DisassembleAt(t *testing.T) {
+	c := NewIntCodeComputer(strings.Split(`1002,4,3,4,33`, ","), nil, nil, nil, false, nil)
+	inst, ok := c.DisassembleAt(0)
+	assert.True(t, ok)
+	assert.Equal(t, "MUL  [4], #3, [4]", inst.String())
+	_, ok = c.DisassembleAt(4)
+	assert.False(t, ok)
+}
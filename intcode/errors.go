@@ -0,0 +1,54 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidOpcode        = errors.New("invalid opcode")
+	ErrInvalidParameterMode = errors.New("invalid parameter mode")
+	ErrNegativeAddress      = errors.New("negative address")
+	ErrAddress              = errors.New("address out of range")
+	ErrImmediateWrite       = errors.New("write to immediate mode parameter")
+	ErrUnparsableCell       = errors.New("unparsable cell")
+	ErrAddressOverflow      = errors.New("value too large to be used as an address")
+	ErrOverflow             = errors.New("result does not fit in 64 bits")
+	ErrSparseMemory         = errors.New("memory too large for the program format")
+)
+
+// Fault is returned by Execute when the program does something the computer can't run.  Err is one
+// of the Err values above, or the error an extension's handler returned, so faults can be matched with
+// errors.Is.
+type Fault struct {
+	Err          error
+	Ptr          int
+	Instruction  string // raw value of the cell at Ptr
+	RelativeBase int
+	Detail       string
+}
+
+func (f *Fault) Error() string {
+	s := fmt.Sprintf("%s at pos %d (instruction %s, relative base %d)", f.Err, f.Ptr, f.Instruction, f.RelativeBase)
+	if f.Detail != "" {
+		s += ": " + f.Detail
+	}
+	return s
+}
+
+func (f *Fault) Unwrap() error {
+	return f.Err
+}
+
+func (c *IntCodeComputer) newFault(err error, format string, a ...interface{}) *Fault {
+	f := &Fault{
+		Err:          err,
+		Ptr:          c.ptr,
+		RelativeBase: c.relativeBase,
+		Detail:       fmt.Sprintf(format, a...),
+	}
+	if c.ptr >= 0 {
+		f.Instruction = c.getText(c.ptr)
+	}
+	return f
+}
@@ -0,0 +1,194 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var (
+	// ErrOpCodeInUse is returned by RegisterExtension for opcodes the computer already runs.
+	ErrOpCodeInUse = errors.New("opcode already in use")
+	// ErrExtensionStatus faults a program when an extension's handler returns a status it can't.
+	ErrExtensionStatus = errors.New("invalid status from extension")
+)
+
+// Extension defines a custom instruction.  Parameters are decoded like those of the built in
+// instructions, with position, immediate and relative modes, and the handler is called with their
+// resolved addresses.
+//
+// Handlers return Running, ProducedOutput after calling Output, Yielded to give up control without
+// stopping the program, or NeedsInput, before changing anything, when InputText has no value.  An error
+// faults the program with a *Fault that wraps it.
+type Extension struct {
+	OpCode   int    // the opcode, between 1 and 98, which the built in instructions must not use
+	Mnemonic string // the name used when disassembling and profiling
+	Params   int    // the number of parameters, at most 3
+	Writes   []int  // the indexes of the parameters the instruction writes to
+	Handler  func(x *Invocation) (Status, error)
+}
+
+func (x *Extension) name() string {
+	if x.Mnemonic != "" {
+		return x.Mnemonic
+	}
+	return "OP" + strconv.Itoa(x.OpCode)
+}
+
+// RegisterExtension adds a custom instruction to the computer.  Extensions are kept by Clone and Reset.
+func (c *IntCodeComputer) RegisterExtension(x Extension) error {
+	if x.OpCode < 1 || x.OpCode > 98 {
+		return fmt.Errorf("opcode %d out of range", x.OpCode)
+	}
+	if _, ok := c.opCodes[x.OpCode]; ok {
+		return fmt.Errorf("opcode %d: %w", x.OpCode, ErrOpCodeInUse)
+	}
+	if x.Params < 0 || x.Params > maxInstructionLength-1 {
+		return fmt.Errorf("opcode %d: %d parameters", x.OpCode, x.Params)
+	}
+	seen := make(map[int]bool)
+	for _, w := range x.Writes {
+		if w < 0 || w >= x.Params || seen[w] {
+			return fmt.Errorf("opcode %d: invalid write parameter %d", x.OpCode, w)
+		}
+		seen[w] = true
+	}
+	if x.Handler == nil {
+		return fmt.Errorf("opcode %d: no handler", x.OpCode)
+	}
+
+	// the tables may be shared with clones, so they are replaced rather than changed
+	opCodes := make(map[int]int, len(c.opCodes)+1)
+	for k, v := range c.opCodes {
+		opCodes[k] = v
+	}
+	opCodes[x.OpCode] = x.Params + 1
+	extensions := make(map[int]*Extension, len(c.extensions)+1)
+	for k, v := range c.extensions {
+		extensions[k] = v
+	}
+	x.Writes = append([]int(nil), x.Writes...)
+	extensions[x.OpCode] = &x
+
+	c.opCodes = opCodes
+	c.extensions = extensions
+	return nil
+}
+
+// writesParam reports whether the instruction with opCode writes to its parameter i.
+func (c *IntCodeComputer) writesParam(opCode int, i int) bool {
+	if w, ok := c.writeParams[opCode]; ok {
+		return w == i
+	}
+	if x := c.extensions[opCode]; x != nil {
+		for _, w := range x.Writes {
+			if w == i {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *IntCodeComputer) mnemonic(opCode int) string {
+	if x := c.extensions[opCode]; x != nil {
+		return x.name()
+	}
+	return mnemonics[opCode]
+}
+
+// Invocation is an extension instruction being executed.  It gives the handler access to the
+// instruction's parameters and the computer's registers and memory.
+type Invocation struct {
+	c         *IntCodeComputer
+	ptr       int
+	addrs     []int
+	jumped    bool
+	input     string
+	readInput bool
+}
+
+// Computer returns the computer executing the instruction, for example to Peek at memory.
+func (x *Invocation) Computer() *IntCodeComputer {
+	return x.c
+}
+
+// Ptr returns the address of the instruction.
+func (x *Invocation) Ptr() int {
+	return x.ptr
+}
+
+// Addr returns the resolved address of parameter i.
+func (x *Invocation) Addr(i int) int {
+	return x.addrs[i]
+}
+
+// Value returns the value of parameter i.  Use Text for values that need arbitrary precision.
+func (x *Invocation) Value(i int) int64 {
+	return x.c.getValue(x.addrs[i])
+}
+
+func (x *Invocation) Text(i int) string {
+	return x.c.getText(x.addrs[i])
+}
+
+// Set stores v in parameter i, which should be one of the extension's Writes so hooks and the Recorder
+// know about the write.
+func (x *Invocation) Set(i int, v int64) {
+	x.c.setValue(x.addrs[i], v)
+}
+
+// SetText stores a value in the string program format in parameter i.
+func (x *Invocation) SetText(i int, s string) error {
+	return x.c.setText(x.addrs[i], s)
+}
+
+// Jump continues the program at addr instead of the next instruction.
+func (x *Invocation) Jump(addr int) {
+	x.c.ptr = addr
+	x.jumped = true
+}
+
+func (x *Invocation) RelativeBase() int {
+	return x.c.relativeBase
+}
+
+func (x *Invocation) SetRelativeBase(rb int) {
+	x.c.relativeBase = rb
+}
+
+// InputText takes the next queued input value.  It returns false if there is none, in which case the
+// handler should return NeedsInput.
+func (x *Invocation) InputText() (string, bool) {
+	if len(x.c.input) == 0 {
+		return "", false
+	}
+	x.input = x.c.input[0]
+	x.readInput = true
+	x.c.input = x.c.input[1:]
+	return x.input, true
+}
+
+// Output sets the value the instruction outputs.  The handler then returns ProducedOutput.
+func (x *Invocation) Output(v int64) {
+	x.c.output = v
+	x.c.lastOut = strconv.FormatInt(v, 10)
+}
+
+// executeExtension runs the handler of an extension instruction whose parameters resolved to addrs.
+func (c *IntCodeComputer) executeExtension(opCode int, addrs []int) (Status, bool, *Invocation, error) {
+	x := c.extensions[opCode]
+	inv := &Invocation{c: c, ptr: c.ptr, addrs: addrs}
+	status, err := x.Handler(inv)
+	if err != nil {
+		if f, ok := err.(*Fault); ok {
+			return Faulted, false, inv, f
+		}
+		return Faulted, false, inv, c.newFault(err, "in %s", x.name())
+	}
+	switch status {
+	case Running, ProducedOutput, Yielded, NeedsInput:
+		return status, inv.jumped, inv, nil
+	}
+	return Faulted, false, inv, c.newFault(ErrExtensionStatus, "%s returned %s", x.name(), status)
+}
@@ -0,0 +1,145 @@
+package intcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+var errUnknownCall = errors.New("unknown host call")
+
+// testExtensions registers a debug print, a yield, a host call trap, a swap that writes both of its
+// parameters and a negating input instruction.
+func testExtensions(t *testing.T, c *IntCodeComputer, log *bytes.Buffer) {
+	for _, x := range []Extension{
+		{OpCode: 20, Mnemonic: "PRN", Params: 1, Handler: func(x *Invocation) (Status, error) {
+			fmt.Fprintf(log, "%d: %s\n", x.Ptr(), x.Text(0))
+			return Running, nil
+		}},
+		{OpCode: 21, Mnemonic: "YLD", Handler: func(x *Invocation) (Status, error) {
+			return Yielded, nil
+		}},
+		{OpCode: 22, Mnemonic: "TRAP", Params: 2, Writes: []int{1}, Handler: func(x *Invocation) (Status, error) {
+			switch x.Value(0) {
+			case 1:
+				x.Set(1, int64(x.Computer().Instructions()))
+			case 2:
+				x.Output(x.Value(1))
+				return ProducedOutput, nil
+			default:
+				return Running, errUnknownCall
+			}
+			return Running, nil
+		}},
+		{OpCode: 23, Mnemonic: "SWP", Params: 2, Writes: []int{0, 1}, Handler: func(x *Invocation) (Status, error) {
+			a, b := x.Value(0), x.Value(1)
+			x.Set(0, b)
+			x.Set(1, a)
+			return Running, nil
+		}},
+		{OpCode: 24, Mnemonic: "INN", Params: 1, Writes: []int{0}, Handler: func(x *Invocation) (Status, error) {
+			v, ok := x.InputText()
+			if !ok {
+				return NeedsInput, nil
+			}
+			return Running, x.SetText(0, "-"+v)
+		}},
+	} {
+		assert.Nil(t, c.RegisterExtension(x))
+	}
+}
+
+func Test_Extensions(t *testing.T) {
+	program := strings.Split(`24,15,23,15,16,20,15,21,122,2,15,122,1,17,99,0,7,0`, ",")
+	c := NewIntCodeComputer(program, nil, nil, nil, false, nil)
+	var log bytes.Buffer
+	testExtensions(t, c, &log)
+
+	status, err := c.RunUntilOutput()
+	assert.Nil(t, err)
+	assert.Equal(t, NeedsInput, status)
+	assert.Equal(t, 0, c.Registers().Ptr)
+	assert.Equal(t, uint64(0), c.Instructions())
+
+	c.ProvideInput(5)
+	status, _ = c.RunUntilOutput()
+	assert.Equal(t, Yielded, status)
+	assert.Equal(t, "5: 7\n", log.String())
+	assert.Equal(t, int64(7), c.Peek(15))
+	assert.Equal(t, int64(-5), c.Peek(16))
+
+	status, _ = c.RunUntilOutput()
+	assert.Equal(t, ProducedOutput, status)
+	assert.Equal(t, int64(7), c.Output())
+	status, _ = c.RunUntilOutput()
+	assert.Equal(t, Halted, status)
+	assert.Equal(t, int64(6), c.Peek(17))
+
+	inst, ok := c.DisassembleAt(2)
+	assert.True(t, ok)
+	assert.Equal(t, "SWP  [15], [16]", inst.String())
+
+	// extensions are kept by clones, and the compiled engine runs them with the interpreter
+	clone := c.Clone()
+	clone.Reset()
+	clone.SetCompiled(true)
+	clone.ProvideInput(5)
+	assert.Nil(t, clone.Execute())
+	assert.Equal(t, int64(-5), clone.Peek(16))
+
+	assert.True(t, errors.Is(c.RegisterExtension(Extension{OpCode: 4, Handler: c.extensions[20].Handler}), ErrOpCodeInUse))
+	assert.True(t, errors.Is(c.RegisterExtension(Extension{OpCode: 20, Handler: c.extensions[20].Handler}), ErrOpCodeInUse))
+	assert.NotNil(t, c.RegisterExtension(Extension{OpCode: 30, Params: 1, Writes: []int{1}, Handler: c.extensions[20].Handler}))
+	assert.NotNil(t, c.RegisterExtension(Extension{OpCode: 30}))
+	assert.NotNil(t, c.RegisterExtension(Extension{OpCode: 99, Handler: c.extensions[20].Handler}))
+
+	// registering on a clone doesn't change the original
+	assert.Nil(t, clone.RegisterExtension(Extension{OpCode: 30, Handler: c.extensions[21].Handler}))
+	assert.Nil(t, c.extensions[30])
+}
+
+func Test_ExtensionFaults(t *testing.T) {
+	var log bytes.Buffer
+	run := func(program string) error {
+		c := NewIntCodeComputer(strings.Split(program, ","), nil, nil, nil, false, nil)
+		testExtensions(t, c, &log)
+		_, err := c.RunUntilInputNeeded()
+		return err
+	}
+
+	err := run(`122,3,0,99`)
+	assert.True(t, errors.Is(err, errUnknownCall))
+	if f, ok := err.(*Fault); assert.True(t, ok) {
+		assert.Equal(t, 0, f.Ptr)
+		assert.Equal(t, "in TRAP", f.Detail)
+	}
+	assert.True(t, errors.Is(run(`1122,1,0,99`), ErrImmediateWrite))
+	assert.True(t, errors.Is(run(`25,0,99`), ErrInvalidOpcode))
+}
+
+func Test_ExtensionHooks(t *testing.T) {
+	program := strings.Split(`23,5,6,20,5,3,4`, ",")
+	c := NewIntCodeComputer(program, nil, nil, nil, false, nil)
+	var log bytes.Buffer
+	testExtensions(t, c, &log)
+	p := NewProfiler(c)
+	r := NewRecorder(c, 0)
+
+	status, err := c.Step()
+	assert.Nil(t, err)
+	assert.Equal(t, Running, status)
+	assert.Equal(t, uint64(1), p.Writes[5])
+	assert.Equal(t, uint64(1), p.Writes[6])
+	assert.Equal(t, []int64{4, 3}, []int64{c.Peek(5), c.Peek(6)})
+
+	assert.True(t, r.StepBack())
+	assert.Equal(t, []int64{3, 4}, []int64{c.Peek(5), c.Peek(6)})
+	assert.Equal(t, 0, c.Registers().Ptr)
+
+	var report bytes.Buffer
+	assert.Nil(t, p.Report(&report, 1))
+	assert.Contains(t, report.String(), "SWP")
+}
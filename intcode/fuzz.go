@@ -0,0 +1,196 @@
+package intcode
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// Divergence describes the first instruction after which two computers running the same program
+// disagree.
+type Divergence struct {
+	Step        uint64      // the number of instructions both computers had stepped before it
+	Ptr         int         // the address of the instruction
+	Instruction Instruction // the instruction, as decoded by the first computer
+	Detail      string      // what differs
+}
+
+func (d *Divergence) Error() string {
+	return fmt.Sprintf("computers diverge at step %d, ptr %d (%s): %s", d.Step, d.Ptr, d.Instruction, d.Detail)
+}
+
+// Differential steps a and b, which should be loaded with the same program and input, in lock step for
+// at most limit steps or until they stop, and compares their status, error, registers and memory after
+// every step.  It returns the first divergence, or nil if they agree.  Comparing stops early once a value
+// needs more than maxDifferentialBits bits.  It is used to check one engine
+// against another, so a and b are usually configured differently, for example with SetCompiled.
+func Differential(a, b *IntCodeComputer, limit uint64) *Divergence {
+	for step := uint64(0); step < limit; step++ {
+		ptr := a.ptr
+		inst, _ := a.DisassembleAt(ptr)
+		diverge := func(format string, args ...interface{}) *Divergence {
+			return &Divergence{Step: step, Ptr: ptr, Instruction: inst, Detail: fmt.Sprintf(format, args...)}
+		}
+
+		sa, ea := a.Step()
+		sb, eb := b.Step()
+		if sa != sb {
+			return diverge("status %s != %s", sa, sb)
+		}
+		if fmt.Sprint(ea) != fmt.Sprint(eb) {
+			return diverge("error %v != %v", ea, eb)
+		}
+		if ra, rb := a.Registers(), b.Registers(); ra != rb {
+			return diverge("registers %+v != %+v", ra, rb)
+		}
+		if a.memory.length != b.memory.length {
+			return diverge("memory length %d != %d", a.memory.length, b.memory.length)
+		}
+		if addr, ok := a.firstDifference(b); ok {
+			return diverge("memory at %d %s != %s", addr, a.getText(addr), b.getText(addr))
+		}
+		if sa != Running && sa != ProducedOutput && sa != Yielded {
+			return nil
+		}
+		if a.widestBits() > maxDifferentialBits {
+			return nil
+		}
+	}
+	return nil
+}
+
+// maxDifferentialBits stops Differential once a value grows this large, because repeated
+// multiplication with arbitrary precision grows values without bound.
+const maxDifferentialBits = 1 << 12
+
+func (c *IntCodeComputer) widestBits() int {
+	bits := 0
+	for _, b := range c.wide {
+		if b.BitLen() > bits {
+			bits = b.BitLen()
+		}
+	}
+	return bits
+}
+
+// firstDifference returns the lowest address where the memories of c and o differ.  Only allocated
+// pages and wide cells are compared, so sparse memories are cheap to compare.
+func (c *IntCodeComputer) firstDifference(o *IntCodeComputer) (int, bool) {
+	addrs := make([]int, 0, len(c.wide)+len(o.wide))
+	for _, m := range []*pagedMemory{c.memory, o.memory} {
+		for idx := range m.pages {
+			pa, pb := c.memory.pages[idx], o.memory.pages[idx]
+			if pa == pb || (pa != nil && pb != nil && *pa == *pb) {
+				continue
+			}
+			for i := 0; i < pageSize; i++ {
+				var va, vb int64
+				if pa != nil {
+					va = pa[i]
+				}
+				if pb != nil {
+					vb = pb[i]
+				}
+				if va != vb {
+					addrs = append(addrs, idx<<pageBits+i)
+					break
+				}
+			}
+		}
+	}
+	for _, wide := range []map[int]*big.Int{c.wide, o.wide} {
+		for addr := range wide {
+			if c.getBig(addr).Cmp(o.getBig(addr)) != 0 {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	if len(addrs) == 0 {
+		return 0, false
+	}
+	sort.Ints(addrs)
+	return addrs[0], true
+}
+
+// RandomProgram generates a valid program with n instructions followed by a data area, for
+// differential testing.  Operands address the data area, jumps target instructions, and a few writes
+// land in the code, so programs modify themselves.  Programs may loop forever, fault through the
+// relative base or wait for input, so run them with a limit.
+func RandomProgram(r *rand.Rand, n int) []string {
+	lengths := make([]int, n)
+	starts := make([]int, n)
+	opCodes := make([]int, n)
+	codeLen := 0
+	for i := range opCodes {
+		opCodes[i] = 1 + r.Intn(9)
+		if i == n-1 || r.Intn(20) == 0 {
+			opCodes[i] = 99
+		}
+		lengths[i] = opCodeLengths[opCodes[i]]
+		if lengths[i] == 0 {
+			lengths[i] = 1
+		}
+		starts[i] = codeLen
+		codeLen += lengths[i]
+	}
+	dataLen := 16
+	program := make([]string, 0, codeLen+dataLen)
+
+	value := func() string {
+		switch r.Intn(10) {
+		case 0:
+			return strconv.FormatInt(r.Int63n(1<<50)-1<<49, 10)
+		case 1:
+			// needs arbitrary precision
+			return "1" + strconv.FormatInt(r.Int63(), 10) + strconv.Itoa(r.Intn(10))
+		}
+		return strconv.Itoa(r.Intn(20) - 5)
+	}
+
+	for i, opCode := range opCodes {
+		instruction := opCode
+		var params []string
+		for j := 1; j < lengths[i]; j++ {
+			mode := r.Intn(3)
+			w, writes := opCodeWriteParams[opCode]
+			writes = writes && w == j-1
+			if writes && mode == 1 {
+				mode = 0
+			}
+			var p string
+			switch {
+			case (opCode == 5 || opCode == 6) && j == 2 && r.Intn(4) != 0:
+				mode = 1
+				p = strconv.Itoa(starts[r.Intn(n)])
+			case opCode == 9 && mode == 1:
+				p = strconv.Itoa(r.Intn(7) - 3)
+			case mode == 1:
+				p = value()
+			case mode == 0 && writes && r.Intn(10) == 0:
+				p = strconv.Itoa(r.Intn(codeLen))
+			case mode == 0:
+				p = strconv.Itoa(codeLen + r.Intn(dataLen))
+			default:
+				p = strconv.Itoa(codeLen + r.Intn(dataLen) - 2)
+			}
+			instruction += mode * pow10(j+1)
+			params = append(params, p)
+		}
+		program = append(program, strconv.Itoa(instruction))
+		program = append(program, params...)
+	}
+	for i := 0; i < dataLen; i++ {
+		program = append(program, value())
+	}
+	return program
+}
+
+func pow10(n int) int {
+	p := 1
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
@@ -0,0 +1,48 @@
+package intcode
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func Test_DifferentialCompiled(t *testing.T) {
+	r := rand.New(rand.NewSource(2019))
+	for i := 0; i < 500; i++ {
+		program := RandomProgram(r, 5+r.Intn(30))
+		inputs := []int64{r.Int63n(100), r.Int63n(100) - 50, r.Int63n(1 << 40)}
+
+		a := NewIntCodeComputer(program, nil, nil, nil, false, nil)
+		b := NewIntCodeComputer(program, nil, nil, nil, false, nil)
+		b.SetCompiled(true)
+		a.ProvideInput(inputs...)
+		b.ProvideInput(inputs...)
+
+		if d := Differential(a, b, 2000); d != nil {
+			t.Fatalf("%v\nprogram: %s", d, strings.Join(program, ","))
+		}
+	}
+}
+
+func Test_Differential(t *testing.T) {
+	program := strings.Split(`1101,1,2,9,3,10,99,0,0,0,0`, ",")
+	a := NewIntCodeComputer(program, nil, nil, nil, false, nil)
+	b := NewIntCodeComputer(program, nil, nil, nil, false, nil)
+	a.ProvideInput(1)
+	b.ProvideInput(2)
+
+	d := Differential(a, b, 100)
+	if assert.NotNil(t, d) {
+		assert.Equal(t, uint64(1), d.Step)
+		assert.Equal(t, 4, d.Ptr)
+		assert.Equal(t, "IN   [10]", d.Instruction.String())
+		assert.Equal(t, "memory at 10 1 != 2", d.Detail)
+	}
+
+	a.Reset()
+	b.Reset()
+	a.ProvideInput(1)
+	b.ProvideInput(1)
+	assert.Nil(t, Differential(a, b, 100))
+}
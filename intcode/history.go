@@ -0,0 +1,180 @@
+package intcode
+
+// historyEntry holds what an executed instruction changed, so it can be undone.
+type historyEntry struct {
+	ptr            int
+	instructionPtr int
+	relativeBase   int
+	lastOut        string
+	output         int64
+	outputs        int
+	instructions   uint64
+	length         int // the length of memory, which a write past the end grows
+	write          int // address written, or -1
+	old            string
+	more           []historyWrite // further writes by extension instructions
+	input          string
+	readInput      bool
+}
+
+type historyWrite struct {
+	addr int
+	old  string
+}
+
+// Recorder records what each instruction a computer executes changes, so execution can be stepped
+// backwards.  At most the last window instructions are kept, or all of them if window is 0.
+//
+// The history describes the computer's state as it was executed.  Call Clear after changing the state
+// any other way, such as with Reset, Restore or Poke.
+type Recorder struct {
+	c       *IntCodeComputer
+	hooks   *Hooks
+	window  int
+	entries []historyEntry
+	pending historyEntry
+}
+
+func NewRecorder(c *IntCodeComputer, window int) *Recorder {
+	r := &Recorder{c: c, window: window}
+	r.hooks = &Hooks{
+		BeforeInstruction: r.beforeInstruction,
+		AfterInstruction:  r.afterInstruction,
+	}
+	c.AddHooks(r.hooks)
+	return r
+}
+
+// Detach removes the recorder's hooks from the computer.
+func (r *Recorder) Detach() {
+	r.c.RemoveHooks(r.hooks)
+}
+
+// Len returns the number of instructions that can be stepped back.
+func (r *Recorder) Len() int {
+	return len(r.entries)
+}
+
+func (r *Recorder) Clear() {
+	r.entries = nil
+}
+
+func (r *Recorder) beforeInstruction(c *IntCodeComputer, ptr int, instruction int) bool {
+	r.pending = historyEntry{
+		ptr:            ptr,
+		instructionPtr: c.instructionPtr,
+		relativeBase:   c.relativeBase,
+		lastOut:        c.lastOut,
+		output:         c.output,
+		outputs:        len(c.outputs),
+		instructions:   c.instructions,
+		length:         c.memory.length,
+		write:          -1,
+	}
+	return true
+}
+
+func (r *Recorder) afterInstruction(c *IntCodeComputer, e *Executed) {
+	h := r.pending
+	for i, w := range e.writes {
+		if i == 0 {
+			h.write = e.Write
+			h.old = e.Operands[w]
+		} else {
+			h.more = append(h.more, historyWrite{e.Addresses[w], e.Operands[w]})
+		}
+	}
+	if e.readInput {
+		h.input = e.input
+		h.readInput = true
+	}
+	if r.window > 0 && len(r.entries) >= r.window {
+		// drop the oldest entries in bulk so trimming is amortized
+		n := len(r.entries) - r.window + 1
+		if n < r.window/4 {
+			n = r.window / 4
+		}
+		if n < 1 {
+			n = 1
+		}
+		r.entries = append(r.entries[:0], r.entries[n:]...)
+	}
+	r.entries = append(r.entries, h)
+}
+
+// StepBack undoes the last executed instruction.  Input it read is queued again, and outputs it queued
+// for TakeOutputs are removed.  It returns false if there is no history left.
+func (r *Recorder) StepBack() bool {
+	if len(r.entries) == 0 {
+		return false
+	}
+	h := r.entries[len(r.entries)-1]
+	r.entries = r.entries[:len(r.entries)-1]
+
+	c := r.c
+	for i := len(h.more) - 1; i >= 0; i-- {
+		v, b, _ := parseCell(h.more[i].old)
+		c.store(h.more[i].addr, v, b)
+	}
+	if h.write >= 0 {
+		v, b, _ := parseCell(h.old)
+		c.store(h.write, v, b)
+	}
+	c.memory.length = h.length
+	if h.readInput {
+		c.input = append([]string{h.input}, c.input...)
+	}
+	if len(c.outputs) > h.outputs {
+		c.outputs = c.outputs[:h.outputs]
+	}
+	c.ptr = h.ptr
+	c.instructionPtr = h.instructionPtr
+	c.relativeBase = h.relativeBase
+	c.lastOut = h.lastOut
+	c.output = h.output
+	c.instructions = h.instructions
+	c.err = nil
+	return true
+}
+
+// RunBackToWrite steps back to just before the last recorded instruction that wrote to addr.  It returns
+// false, without stepping back, if no recorded instruction wrote to addr.
+func (r *Recorder) RunBackToWrite(addr int) bool {
+	return r.runBackTo(func(h *historyEntry) bool {
+		if h.write == addr {
+			return true
+		}
+		for _, w := range h.more {
+			if w.addr == addr {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// RewindToInput steps back to just before the last recorded input instruction, and removes all queued
+// input so a different value can be provided.  It returns the removed values, the first of which is
+// the one the input instruction read, and false, without stepping back, if no input was recorded.
+func (r *Recorder) RewindToInput() ([]string, bool) {
+	if !r.runBackTo(func(h *historyEntry) bool { return h.readInput }) {
+		return nil, false
+	}
+	input := r.c.input
+	r.c.input = nil
+	return input, true
+}
+
+func (r *Recorder) runBackTo(match func(h *historyEntry) bool) bool {
+	i := len(r.entries) - 1
+	for i >= 0 && !match(&r.entries[i]) {
+		i--
+	}
+	if i < 0 {
+		return false
+	}
+	for len(r.entries) > i {
+		r.StepBack()
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package intcode
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_RecorderStepBack(t *testing.T) {
+	// echoes every input doubled, forever
+	c := NewIntCodeComputer(strings.Split(`3,11,1002,11,2,11,4,11,1105,1,0,0`, ","), nil, nil, nil, false, nil)
+	r := NewRecorder(c, 0)
+
+	c.ProvideInput(1, 2)
+	status, _ := c.RunUntilInputNeeded()
+	assert.Equal(t, NeedsInput, status)
+	assert.Equal(t, 8, r.Len())
+	before := c.Snapshot()
+
+	c.ProvideInput(3)
+	c.RunUntilInputNeeded()
+	assert.Equal(t, []int64{2, 4, 6}, c.TakeOutputs())
+
+	// undo the third echo: IN, MUL, OUT and JNZ
+	for i := 0; i < 4; i++ {
+		assert.True(t, r.StepBack())
+	}
+	assert.Equal(t, before.Memory, c.MemoryRuns())
+	assert.Equal(t, before.Ptr, c.Registers().Ptr)
+	assert.Equal(t, "4", c.OutputText())
+	assert.Equal(t, before.Instructions, c.Instructions())
+
+	// the input read is queued again, so running forward replays it
+	c.RunUntilInputNeeded()
+	assert.Equal(t, []int64{6}, c.TakeOutputs())
+
+	assert.True(t, r.RunBackToWrite(11))
+	assert.Equal(t, 2, c.Registers().Ptr)
+	assert.Equal(t, int64(3), c.Peek(11))
+
+	input, ok := r.RewindToInput()
+	assert.True(t, ok)
+	assert.Equal(t, []string{"3"}, input)
+	assert.Equal(t, 0, c.Registers().Ptr)
+	status, _ = c.Step()
+	assert.Equal(t, NeedsInput, status)
+	c.ProvideInput(50)
+	c.RunUntilOutput()
+	assert.Equal(t, int64(100), c.Output())
+
+	for r.StepBack() {
+	}
+	assert.Equal(t, strings.Split(`3,11,1002,11,2,11,4,11,1105,1,0,0`, ","), programOf(c))
+	assert.Equal(t, uint64(0), c.Instructions())
+	_, ok = r.RewindToInput()
+	assert.False(t, ok)
+	assert.False(t, r.RunBackToWrite(11))
+}
+
+func Test_RecorderStepBackLength(t *testing.T) {
+	// writes past the end of the program
+	program := strings.Split(`1101,1,2,10,99`, ",")
+	c := NewIntCodeComputer(program, nil, nil, nil, false, nil)
+	r := NewRecorder(c, 0)
+
+	c.Step()
+	assert.Equal(t, 11, c.MemoryStats().Length)
+	assert.True(t, r.StepBack())
+	assert.Equal(t, 5, c.MemoryStats().Length)
+	assert.Equal(t, program, programOf(c))
+}
+
+func Test_RecorderWindow(t *testing.T) {
+	// counts forever
+	c := NewIntCodeComputer(strings.Split(`1001,7,1,7,1105,1,0,0`, ","), nil, nil, nil, false, nil)
+	r := NewRecorder(c, 100)
+
+	c.SetInstructionBudget(1000)
+	c.RunUntilInputNeeded()
+	assert.True(t, r.Len() <= 100)
+	assert.True(t, r.Len() > 50)
+
+	// wide values are restored exactly
+	c.SetInstructionBudget(0)
+	c.SetBigIntMode(true)
+	c.Poke(7, 9223372036854775807)
+	r.Clear()
+	for !c.isWide(7) {
+		c.Step()
+	}
+	c.Step()
+	c.Step()
+	assert.Equal(t, "9223372036854775809", c.PeekText(7))
+	r.StepBack()
+	r.StepBack()
+	assert.Equal(t, "9223372036854775808", c.PeekText(7))
+	r.StepBack()
+	r.StepBack()
+	assert.Equal(t, "9223372036854775807", c.PeekText(7))
+	assert.False(t, c.isWide(7))
+}
@@ -0,0 +1,197 @@
+package intcode
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrPaused is returned by ExecuteContext when a hook paused the computer.  Executing again resumes
+// at the instruction that was about to run.
+var ErrPaused = errors.New("paused by hook")
+
+// Hooks are called by the computer as it runs.  Any of the functions may be nil.
+type Hooks struct {
+	// BeforeInstruction is called before the instruction at ptr is executed.  Returning false pauses
+	// the computer without executing it.
+	BeforeInstruction func(c *IntCodeComputer, ptr int, instruction int) bool
+	// AfterWrite is called after a program writes to a memory cell.  Values that need arbitrary
+	// precision are reported as 0.
+	AfterWrite func(c *IntCodeComputer, addr int, old, new int64)
+	// AfterInstruction is called after an instruction is executed.
+	AfterInstruction func(c *IntCodeComputer, e *Executed)
+}
+
+// Executed describes an executed instruction.
+type Executed struct {
+	Ptr         int
+	Instruction int
+	OpCode      int
+	// Addresses holds the resolved address of each parameter
+	Addresses []int
+	// Operands holds the value of each parameter before the instruction ran
+	Operands []string
+	// Write is the address written to, or -1.  Extension instructions may write to more than one
+	// parameter; Writes returns all of them.
+	Write int
+	// Result is the value written or output, the jump target if a jump was taken, or the new relative
+	// base
+	Result string
+
+	writes    []int // the indexes of the parameters written to
+	input     string
+	readInput bool
+}
+
+// Reads returns the addresses the instruction read from.
+func (e *Executed) Reads() []int {
+	var reads []int
+	for i, addr := range e.Addresses {
+		if e.writesParam(i) {
+			continue
+		}
+		if (e.OpCode == 5 || e.OpCode == 6) && i == 1 && e.Result == "" {
+			continue
+		}
+		reads = append(reads, addr)
+	}
+	return reads
+}
+
+// Writes returns the addresses the instruction wrote to.
+func (e *Executed) Writes() []int {
+	writes := make([]int, len(e.writes))
+	for i, w := range e.writes {
+		writes[i] = e.Addresses[w]
+	}
+	return writes
+}
+
+func (e *Executed) writesParam(i int) bool {
+	for _, w := range e.writes {
+		if w == i {
+			return true
+		}
+	}
+	return false
+}
+
+// AddHooks registers h with the computer.  Hooks are called in the order they were added.
+func (c *IntCodeComputer) AddHooks(h *Hooks) {
+	c.hooks = append(c.hooks, h)
+}
+
+func (c *IntCodeComputer) RemoveHooks(h *Hooks) {
+	for i := range c.hooks {
+		if c.hooks[i] == h {
+			c.hooks = append(c.hooks[:i:i], c.hooks[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *IntCodeComputer) beforeInstruction(instruction int) bool {
+	proceed := true
+	for _, h := range c.hooks {
+		if h.BeforeInstruction != nil && !h.BeforeInstruction(c, c.ptr, instruction) {
+			proceed = false
+		}
+	}
+	return proceed
+}
+
+func (c *IntCodeComputer) afterWrite(addr int, old, new int64) {
+	for _, h := range c.hooks {
+		if h.AfterWrite != nil {
+			h.AfterWrite(c, addr, old, new)
+		}
+	}
+}
+
+// singleParams holds the write parameter indexes of the built in instructions, so they aren't allocated
+// for every instruction.
+var singleParams = [][]int{{0}, {1}, {2}}
+
+func (c *IntCodeComputer) beginExecuted(opCode int, paramPositions []int) *Executed {
+	e := &Executed{
+		Ptr:         c.ptr,
+		Instruction: int(c.getValue(c.ptr)),
+		OpCode:      opCode,
+		Addresses:   make([]int, len(paramPositions)),
+		Operands:    make([]string, len(paramPositions)),
+		Write:       -1,
+	}
+	copy(e.Addresses, paramPositions)
+	for i, pos := range paramPositions {
+		e.Operands[i] = c.getText(pos)
+	}
+	if w, ok := c.writeParams[opCode]; ok {
+		e.writes = singleParams[w]
+	} else if x := c.extensions[opCode]; x != nil {
+		e.writes = x.Writes
+	}
+	if len(e.writes) > 0 {
+		e.Write = paramPositions[e.writes[0]]
+	}
+	return e
+}
+
+func (c *IntCodeComputer) endExecuted(e *Executed, status Status, jumped bool) {
+	switch {
+	case e.Write >= 0:
+		e.Result = c.getText(e.Write)
+	case status == ProducedOutput:
+		e.Result = c.lastOut
+	case jumped:
+		e.Result = strconv.Itoa(c.ptr)
+	case e.OpCode == 9:
+		e.Result = strconv.Itoa(c.relativeBase)
+	}
+	if e.OpCode == 3 {
+		e.input = e.Result
+		e.readInput = true
+	}
+	for _, h := range c.hooks {
+		if h.AfterInstruction != nil {
+			h.AfterInstruction(c, e)
+		}
+	}
+}
+
+// Registers holds the computer's registers.
+type Registers struct {
+	Ptr          int
+	RelativeBase int
+	LastOut      string
+	Instructions uint64
+}
+
+func (c *IntCodeComputer) Registers() Registers {
+	return Registers{
+		Ptr:          c.ptr,
+		RelativeBase: c.relativeBase,
+		LastOut:      c.lastOut,
+		Instructions: c.instructions,
+	}
+}
+
+// Peek returns the value at addr.  Use PeekText for values that need arbitrary precision.
+func (c *IntCodeComputer) Peek(addr int) int64 {
+	if addr < 0 {
+		return 0
+	}
+	return c.getValue(addr)
+}
+
+func (c *IntCodeComputer) PeekText(addr int) string {
+	if addr < 0 {
+		return "0"
+	}
+	return c.getText(addr)
+}
+
+// Poke stores val at addr without calling any hooks.
+func (c *IntCodeComputer) Poke(addr int, val int64) {
+	if addr >= 0 && addr <= MaxAddress {
+		c.store(addr, val, nil)
+	}
+}
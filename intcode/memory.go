@@ -0,0 +1,466 @@
+package intcode
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// parseCell converts a program cell to its int64 value, or to a big.Int when it does not fit in 64 bits.
+func parseCell(s string) (int64, *big.Int, error) {
+	s = strings.TrimSpace(s)
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return v, nil, nil
+	}
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+		if b, ok := new(big.Int).SetString(s, 10); ok {
+			return 0, b, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("%q is not an integer", s)
+}
+
+// parseProgram converts a string program into int64 memory plus the cells that needed arbitrary precision.
+func parseProgram(program []string) ([]int64, map[int]*big.Int, error) {
+	memory := make([]int64, len(program), len(program))
+	var wide map[int]*big.Int
+	for i, s := range program {
+		v, b, err := parseCell(s)
+		if err != nil {
+			return nil, nil, &Fault{Err: ErrUnparsableCell, Ptr: i, Instruction: s, Detail: err.Error()}
+		}
+		if b != nil {
+			if wide == nil {
+				wide = make(map[int]*big.Int)
+			}
+			wide[i] = b
+		}
+		memory[i] = v
+	}
+	return memory, wide, nil
+}
+
+func addOverflows(a, b, r int64) bool {
+	return (a > 0 && b > 0 && r < 0) || (a < 0 && b < 0 && r >= 0)
+}
+
+func mulOverflows(a, b, r int64) bool {
+	if a == 0 || b == 0 {
+		return false
+	}
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return true
+	}
+	return r/b != a
+}
+
+// MaxAddress is the highest address a program can use, so the length of memory, one past the highest
+// address written, always fits in an int.
+const MaxAddress = int(^uint(0)>>1) - 1
+
+const (
+	pageBits = 10
+	pageSize = 1 << pageBits
+	pageMask = pageSize - 1
+)
+
+type page [pageSize]int64
+
+// pagedMemory is a sparse memory made of fixed size pages.  Pages are only allocated when a non zero
+// value is written to them, so unwritten cells read as zero and cost nothing.
+//
+// Pages are shared copy on write between a memory and its clones.  A memory only writes to the pages
+// it owns, and copies a shared page the first time it writes to it.
+type pagedMemory struct {
+	pages     map[int]*page
+	owned     map[int]bool
+	length    int // one past the highest address that has been written
+	lastIdx   int
+	lastPage  *page
+	lastOwned bool
+	code      *compiledCode // instructions compiled from this memory, if the compiled engine is used
+}
+
+func newPagedMemory(values []int64) *pagedMemory {
+	m := new(pagedMemory)
+	m.pages = make(map[int]*page)
+	m.owned = make(map[int]bool)
+	m.lastIdx = -1
+	for i, v := range values {
+		if v != 0 {
+			m.set(i, v)
+		}
+	}
+	m.length = len(values)
+	return m
+}
+
+// clone returns a memory that shares all of m's pages and compiled instructions.  Neither memory owns the shared pages
+// afterwards, so whichever writes to a page first copies it.
+func (m *pagedMemory) clone() *pagedMemory {
+	c := new(pagedMemory)
+	c.pages = make(map[int]*page, len(m.pages))
+	for idx, p := range m.pages {
+		c.pages[idx] = p
+	}
+	c.owned = make(map[int]bool)
+	c.length = m.length
+	c.lastIdx = -1
+	if m.code != nil {
+		c.code = m.code.clone()
+	}
+	m.owned = make(map[int]bool)
+	m.lastIdx = -1
+	return c
+}
+
+// pageFor returns the page holding pos, or nil if it hasn't been allocated.
+func (m *pagedMemory) pageFor(pos int) *page {
+	idx := pos >> pageBits
+	if idx == m.lastIdx {
+		return m.lastPage
+	}
+	p, ok := m.pages[idx]
+	if !ok {
+		return nil
+	}
+	m.lastIdx = idx
+	m.lastPage = p
+	m.lastOwned = m.owned[idx]
+	return p
+}
+
+// writablePage returns the page holding pos, allocating it, or copying it if it is shared.
+func (m *pagedMemory) writablePage(pos int) *page {
+	idx := pos >> pageBits
+	if idx == m.lastIdx && m.lastOwned {
+		return m.lastPage
+	}
+	p, ok := m.pages[idx]
+	if !ok {
+		p = new(page)
+	} else if !m.owned[idx] {
+		cp := *p
+		p = &cp
+	}
+	m.pages[idx] = p
+	m.owned[idx] = true
+	m.lastIdx = idx
+	m.lastPage = p
+	m.lastOwned = true
+	return p
+}
+
+func (m *pagedMemory) get(pos int) int64 {
+	if p := m.pageFor(pos); p != nil {
+		return p[pos&pageMask]
+	}
+	return 0
+}
+
+// set stores val at pos, which must not be above MaxAddress.
+func (m *pagedMemory) set(pos int, val int64) {
+	if m.code != nil {
+		m.code.invalidate(pos)
+	}
+	if pos >= m.length {
+		m.length = pos + 1
+	}
+	if val == 0 && m.pageFor(pos) == nil {
+		return
+	}
+	m.writablePage(pos)[pos&pageMask] = val
+}
+
+// MemoryStats describes how much memory a computer is using.
+type MemoryStats struct {
+	Length    int // one past the highest address that has been written
+	Pages     int // number of allocated pages
+	PageSize  int // number of cells in a page
+	Shared    int // number of pages shared with clones, which are copied on the first write
+	WideCells int // number of cells that need arbitrary precision
+	Bytes     int // approximate number of bytes used by the allocated pages, including shared ones
+}
+
+func (c *IntCodeComputer) MemoryStats() MemoryStats {
+	return MemoryStats{
+		Length:    c.memory.length,
+		Pages:     len(c.memory.pages),
+		PageSize:  pageSize,
+		Shared:    len(c.memory.pages) - len(c.memory.owned),
+		WideCells: len(c.wide),
+		Bytes:     len(c.memory.pages) * pageSize * 8,
+	}
+}
+
+func (c *IntCodeComputer) getValue(pos int) int64 {
+	return c.memory.get(pos)
+}
+
+func (c *IntCodeComputer) setValue(pos int, val int64) {
+	if c.wide != nil {
+		delete(c.wide, pos)
+	}
+	if c.hooks != nil {
+		old := c.memory.get(pos)
+		c.memory.set(pos, val)
+		c.afterWrite(pos, old, val)
+		return
+	}
+	c.memory.set(pos, val)
+}
+
+func (c *IntCodeComputer) isWide(pos int) bool {
+	if c.wide == nil {
+		return false
+	}
+	_, ok := c.wide[pos]
+	return ok
+}
+
+// getBig returns the value at pos with arbitrary precision.
+func (c *IntCodeComputer) getBig(pos int) *big.Int {
+	if b, ok := c.wide[pos]; ok {
+		return b
+	}
+	return big.NewInt(c.getValue(pos))
+}
+
+// setBig stores val at pos, keeping it as int64 whenever it fits.
+func (c *IntCodeComputer) setBig(pos int, val *big.Int) {
+	if val.IsInt64() {
+		c.setValue(pos, val.Int64())
+		return
+	}
+	c.setValue(pos, 0)
+	if c.wide == nil {
+		c.wide = make(map[int]*big.Int)
+	}
+	c.wide[pos] = val
+}
+
+// getAddress returns the value at pos for use as an address, jump target or relative base offset.
+// It fails if the value needs arbitrary precision.
+func (c *IntCodeComputer) getAddress(pos int) (int, error) {
+	if c.isWide(pos) {
+		return 0, c.newFault(ErrAddressOverflow, "value %s at pos %d", c.wide[pos].Text(10), pos)
+	}
+	return int(c.getValue(pos)), nil
+}
+
+func (c *IntCodeComputer) getText(pos int) string {
+	if c.isWide(pos) {
+		return c.wide[pos].Text(10)
+	}
+	return strconv.FormatInt(c.getValue(pos), 10)
+}
+
+// store writes a value without calling hooks.  b holds the value if it needs arbitrary precision.
+func (c *IntCodeComputer) store(pos int, val int64, b *big.Int) {
+	if c.wide != nil {
+		delete(c.wide, pos)
+	}
+	if b != nil {
+		c.memory.set(pos, 0)
+		if c.wide == nil {
+			c.wide = make(map[int]*big.Int)
+		}
+		c.wide[pos] = b
+		return
+	}
+	c.memory.set(pos, val)
+}
+
+// setText stores a value in the string program format at pos.  It faults with ErrUnparsableCell if s
+// isn't an integer, and with ErrOverflow if it doesn't fit in 64 bits outside of arbitrary precision mode.
+func (c *IntCodeComputer) setText(pos int, s string) error {
+	v, b, err := parseCell(s)
+	if err != nil {
+		return c.newFault(ErrUnparsableCell, "%s", err)
+	}
+	if b != nil {
+		if !c.bigMode {
+			return c.newFault(ErrOverflow, "%s needs arbitrary precision mode", s)
+		}
+		c.setBig(pos, b)
+	} else {
+		c.setValue(pos, v)
+	}
+	return nil
+}
+
+// add stores a+b at dst.  Outside of arbitrary precision mode a result that doesn't fit in 64 bits
+// faults with ErrOverflow, leaving dst unchanged.
+func (c *IntCodeComputer) add(dst, a, b int) error {
+	if c.bigMode && (c.isWide(a) || c.isWide(b)) {
+		c.setBig(dst, new(big.Int).Add(c.getBig(a), c.getBig(b)))
+		return nil
+	}
+	va, vb := c.getValue(a), c.getValue(b)
+	r := va + vb
+	if addOverflows(va, vb, r) {
+		if !c.bigMode {
+			return c.newFault(ErrOverflow, "%d + %d", va, vb)
+		}
+		c.setBig(dst, new(big.Int).Add(big.NewInt(va), big.NewInt(vb)))
+		return nil
+	}
+	c.setValue(dst, r)
+	return nil
+}
+
+// mul stores a*b at dst.  Outside of arbitrary precision mode a result that doesn't fit in 64 bits
+// faults with ErrOverflow, leaving dst unchanged.
+func (c *IntCodeComputer) mul(dst, a, b int) error {
+	if c.bigMode && (c.isWide(a) || c.isWide(b)) {
+		c.setBig(dst, new(big.Int).Mul(c.getBig(a), c.getBig(b)))
+		return nil
+	}
+	va, vb := c.getValue(a), c.getValue(b)
+	r := va * vb
+	if mulOverflows(va, vb, r) {
+		if !c.bigMode {
+			return c.newFault(ErrOverflow, "%d * %d", va, vb)
+		}
+		c.setBig(dst, new(big.Int).Mul(big.NewInt(va), big.NewInt(vb)))
+		return nil
+	}
+	c.setValue(dst, r)
+	return nil
+}
+
+// cmp compares the values at a and b, returning -1, 0 or +1.
+func (c *IntCodeComputer) cmp(a, b int) int {
+	if c.isWide(a) || c.isWide(b) {
+		return c.getBig(a).Cmp(c.getBig(b))
+	}
+	va, vb := c.getValue(a), c.getValue(b)
+	if va < vb {
+		return -1
+	} else if va > vb {
+		return 1
+	}
+	return 0
+}
+
+// isZero reports whether the value at pos is zero.
+func (c *IntCodeComputer) isZero(pos int) bool {
+	if c.isWide(pos) {
+		return false
+	}
+	return c.getValue(pos) == 0
+}
+
+// formatProgram converts parsed program values back into the string program format.
+func formatProgram(memory []int64, wide map[int]*big.Int) []string {
+	program := make([]string, len(memory))
+	for i, v := range memory {
+		if b, ok := wide[i]; ok {
+			program[i] = b.String()
+		} else {
+			program[i] = strconv.FormatInt(v, 10)
+		}
+	}
+	return program
+}
+
+// maxDenseLength is the longest memory formatMemory will convert, since the string program format
+// spells out every cell, including the unwritten ones sparse memory doesn't store.
+const maxDenseLength = 1 << 24
+
+// formatMemory converts memory back into the string program format.
+func (c *IntCodeComputer) formatMemory() ([]string, error) {
+	if c.memory.length > maxDenseLength {
+		return nil, fmt.Errorf("%w: memory is %d cells long", ErrSparseMemory, c.memory.length)
+	}
+	program := make([]string, c.memory.length, c.memory.length)
+	for i := range program {
+		program[i] = c.getText(i)
+	}
+	return program, nil
+}
+
+// MemoryRun is a run of consecutive memory cells in the string program format, starting at Addr.
+type MemoryRun struct {
+	Addr   int      `json:"addr"`
+	Values []string `json:"values"`
+}
+
+// MemoryRuns returns the cells of memory that aren't zero, in runs ordered by address.  Unlike
+// GetProgram it only costs as much as the pages that are in use.
+func (c *IntCodeComputer) MemoryRuns() []MemoryRun {
+	used := make(map[int]bool, len(c.memory.pages))
+	for idx := range c.memory.pages {
+		used[idx] = true
+	}
+	for pos := range c.wide {
+		used[pos>>pageBits] = true
+	}
+	pages := sortedKeys(used)
+
+	var runs []MemoryRun
+	for i, idx := range pages {
+		start := idx << pageBits
+		end := start + pageSize
+		if end > c.memory.length {
+			end = c.memory.length
+		}
+		if i == 0 || pages[i-1] != idx-1 {
+			runs = append(runs, MemoryRun{Addr: start})
+		}
+		run := &runs[len(runs)-1]
+		for pos := start; pos < end; pos++ {
+			run.Values = append(run.Values, c.getText(pos))
+		}
+	}
+
+	// trim the zeros at either end of the runs
+	trimmed := runs[:0]
+	for _, run := range runs {
+		for len(run.Values) > 0 && run.Values[0] == "0" {
+			run.Addr++
+			run.Values = run.Values[1:]
+		}
+		for len(run.Values) > 0 && run.Values[len(run.Values)-1] == "0" {
+			run.Values = run.Values[:len(run.Values)-1]
+		}
+		if len(run.Values) > 0 {
+			trimmed = append(trimmed, run)
+		}
+	}
+	return trimmed
+}
+
+// parseMemoryRuns converts runs written by MemoryRuns back into a memory of length cells.
+func parseMemoryRuns(runs []MemoryRun, length int) (*pagedMemory, map[int]*big.Int, error) {
+	if length < 0 || length > MaxAddress+1 {
+		return nil, nil, fmt.Errorf("memory length %d is out of range", length)
+	}
+	m := newPagedMemory(nil)
+	var wide map[int]*big.Int
+	for _, run := range runs {
+		if run.Addr < 0 || run.Addr > length-len(run.Values) {
+			return nil, nil, fmt.Errorf("memory run at %d of %d cells is outside memory", run.Addr, len(run.Values))
+		}
+		for i, s := range run.Values {
+			pos := run.Addr + i
+			v, b, err := parseCell(s)
+			if err != nil {
+				return nil, nil, &Fault{Err: ErrUnparsableCell, Ptr: pos, Instruction: s, Detail: err.Error()}
+			}
+			if b != nil {
+				if wide == nil {
+					wide = make(map[int]*big.Int)
+				}
+				wide[pos] = b
+			} else if v != 0 {
+				m.set(pos, v)
+			}
+		}
+	}
+	m.length = length
+	return m, wide, nil
+}
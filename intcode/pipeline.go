@@ -0,0 +1,250 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// ErrNoOutput is returned by Pipeline.Run when the output nodes didn't output anything.
+var ErrNoOutput = errors.New("pipeline produced no output")
+
+// ErrNoNode is returned by Pipeline.Run when the pipeline was built with a node index it doesn't have.
+var ErrNoNode = errors.New("no such pipeline node")
+
+// ErrNoPhases is returned for a pipeline or a search without any phases.
+var ErrNoPhases = errors.New("no phases")
+
+// Pipeline is a graph of computers whose outputs feed each other's inputs, like day7's amplifiers.  A
+// node whose output is connected to several nodes sends each of them every value, and a node connected
+// from several nodes reads their values in the order they are produced.  Pipelines are run by a Scheduler
+// on one goroutine, so the results are always the same.  Mistakes building a pipeline, such as connecting
+// a node that doesn't exist, are returned by Run.
+type Pipeline struct {
+	nodes []*pipelineNode
+	last  []int64
+	err   error
+}
+
+type pipelineNode struct {
+	computer *IntCodeComputer
+	inputs   []int64
+	targets  []int
+	output   bool
+}
+
+func NewPipeline() *Pipeline {
+	return new(Pipeline)
+}
+
+// AddNode adds a computer running program that starts with inputs queued, such as a phase setting.  It
+// returns the node's index.
+func (p *Pipeline) AddNode(program []string, inputs ...int64) int {
+	p.nodes = append(p.nodes, &pipelineNode{
+		computer: NewIntCodeComputer(program, nil, nil, nil, false, nil),
+		inputs:   append([]int64(nil), inputs...),
+	})
+	return len(p.nodes) - 1
+}
+
+// Input queues more initial inputs for node, after the ones it was added with.
+func (p *Pipeline) Input(node int, values ...int64) {
+	if p.valid(node) {
+		p.nodes[node].inputs = append(p.nodes[node].inputs, values...)
+	}
+}
+
+// Connect sends the outputs of from to the input of to.
+func (p *Pipeline) Connect(from, to int) {
+	if p.valid(from, to) {
+		p.nodes[from].targets = append(p.nodes[from].targets, to)
+	}
+}
+
+// Output collects the outputs of node as the pipeline's output.
+func (p *Pipeline) Output(node int) {
+	if p.valid(node) {
+		p.nodes[node].output = true
+	}
+}
+
+// valid reports whether the pipeline has the nodes, remembering the first that it doesn't have for Run
+// to return.
+func (p *Pipeline) valid(nodes ...int) bool {
+	for _, n := range nodes {
+		if n < 0 || n >= len(p.nodes) {
+			if p.err == nil {
+				p.err = fmt.Errorf("%w: %d", ErrNoNode, n)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// Chain returns a pipeline of one node per phase, each running program and feeding the next, with input
+// sent to the first after its phase.  The last node is the output.  Without phases, Run returns
+// ErrNoPhases.
+func Chain(program []string, phases []int64, input int64) *Pipeline {
+	p := NewPipeline()
+	if len(phases) == 0 {
+		p.err = ErrNoPhases
+		return p
+	}
+	for i, phase := range phases {
+		p.AddNode(program, phase)
+		if i > 0 {
+			p.Connect(i-1, i)
+		}
+	}
+	p.Input(0, input)
+	p.Output(len(phases) - 1)
+	return p
+}
+
+// Loop is like Chain, but the last node also feeds the first, as a feedback loop.
+func Loop(program []string, phases []int64, input int64) *Pipeline {
+	p := Chain(program, phases, input)
+	if len(phases) > 0 {
+		p.Connect(len(phases)-1, 0)
+	}
+	return p
+}
+
+// Run runs fresh copies of the nodes until they have all halted, and returns the last value the output
+// nodes produced.  It returns the first fault, ErrDeadlock if the nodes wait for input that never comes,
+// or ErrNoOutput.  A pipeline must not be run by more than one goroutine at once.
+func (p *Pipeline) Run() (int64, error) {
+	p.last = nil
+	if p.err != nil {
+		return 0, p.err
+	}
+	s := NewScheduler(0)
+	tasks := make([]*Task, len(p.nodes))
+	for i, n := range p.nodes {
+		c := n.computer.Clone()
+		c.ProvideInput(n.inputs...)
+		n := n
+		tasks[i] = s.Add(c, nil, func(t *Task, value int64) {
+			for _, to := range n.targets {
+				tasks[to].Send(value)
+			}
+			if n.output {
+				p.last = append(p.last, value)
+			}
+		})
+	}
+	if err := s.Run(); err != nil {
+		return 0, err
+	}
+	if len(p.last) == 0 {
+		return 0, ErrNoOutput
+	}
+	return p.last[len(p.last)-1], nil
+}
+
+// Outputs returns every value the output nodes produced in the last Run.
+func (p *Pipeline) Outputs() []int64 {
+	return p.last
+}
+
+// Objective says whether SearchPhases looks for the highest or lowest result.
+type Objective int
+
+const (
+	Maximize Objective = iota
+	Minimize
+)
+
+// SearchPhases calls run with every ordering of phases, in parallel, and returns the ordering with the
+// best result and the result.  Ties go to the ordering that comes first with phases permuted in
+// lexicographic order.  An error from run stops the search and is returned, and so is ErrNoPhases if
+// there are no phases to order.
+func SearchPhases(phases []int64, objective Objective, run func(phases []int64) (int64, error)) ([]int64, int64, error) {
+	if objective != Maximize && objective != Minimize {
+		return nil, 0, fmt.Errorf("unknown objective %d", objective)
+	}
+	if len(phases) == 0 {
+		return nil, 0, ErrNoPhases
+	}
+	orders := permutations(len(phases))
+	results := make([]int64, len(orders))
+	errs := make([]error, len(orders))
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	var failed bool
+	var mu sync.Mutex
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				order := make([]int64, len(phases))
+				for j, k := range orders[i] {
+					order[j] = phases[k]
+				}
+				results[i], errs[i] = run(order)
+				if errs[i] != nil {
+					mu.Lock()
+					failed = true
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range orders {
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if stop {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	best := -1
+	for i := range orders {
+		if errs[i] != nil {
+			return nil, 0, errs[i]
+		}
+		if best < 0 || (objective == Maximize && results[i] > results[best]) || (objective == Minimize && results[i] < results[best]) {
+			best = i
+		}
+	}
+	order := make([]int64, len(phases))
+	for j, k := range orders[best] {
+		order[j] = phases[k]
+	}
+	return order, results[best], nil
+}
+
+// permutations returns every ordering of the indexes 0 to n-1, in lexicographic order.
+func permutations(n int) [][]int {
+	p := make([]int, n)
+	for i := range p {
+		p[i] = i
+	}
+	var perms [][]int
+	for {
+		perms = append(perms, append([]int(nil), p...))
+		i := n - 2
+		for i >= 0 && p[i] > p[i+1] {
+			i--
+		}
+		if i < 0 {
+			return perms
+		}
+		j := n - 1
+		for p[j] < p[i] {
+			j--
+		}
+		p[i], p[j] = p[j], p[i]
+		for l, r := i+1, n-1; l < r; l, r = l+1, r-1 {
+			p[l], p[r] = p[r], p[l]
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package intcode
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_Pipeline(t *testing.T) {
+	chain := strings.Split(`3,15,3,16,1002,16,10,16,1,16,15,15,4,15,99,0,0`, ",")
+	signal, err := Chain(chain, []int64{4, 3, 2, 1, 0}, 0).Run()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(43210), signal)
+
+	loop := strings.Split(`3,52,1001,52,-5,52,3,53,1,52,56,54,1007,54,5,55,1005,55,26,1001,54,-5,54,1105,1,12,1,53,54,53,1008,54,0,55,1001,55,1,55,2,53,55,53,4,53,1001,56,-1,56,1005,56,6,99,0,0,0,0,10`, ",")
+	p := Loop(loop, []int64{9, 7, 8, 5, 6}, 0)
+	signal, err = p.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(18216), signal)
+	assert.Equal(t, 10, len(p.Outputs()))
+
+	// running again starts from scratch
+	signal, err = p.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(18216), signal)
+
+	// without the first signal the loop never starts
+	p = NewPipeline()
+	a := p.AddNode(loop, 9)
+	b := p.AddNode(loop, 7)
+	p.Connect(a, b)
+	p.Connect(b, a)
+	p.Output(b)
+	_, err = p.Run()
+	assert.Equal(t, ErrDeadlock, err)
+}
+
+func Test_PipelineFanOutFanIn(t *testing.T) {
+	echo := strings.Split(`3,0,4,0,99`, ",")
+	mul := strings.Split(`3,11,3,12,2,11,12,13,4,13,99,0,0,0`, ",")
+	add := strings.Split(`3,11,3,12,1,11,12,13,4,13,99,0,0,0`, ",")
+
+	p := NewPipeline()
+	src := p.AddNode(echo, 7)
+	double := p.AddNode(mul, 2)
+	triple := p.AddNode(mul, 3)
+	sum := p.AddNode(add)
+	p.Connect(src, double)
+	p.Connect(src, triple)
+	p.Connect(double, sum)
+	p.Connect(triple, sum)
+	p.Output(sum)
+	p.Output(double)
+
+	signal, err := p.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(35), signal)
+	assert.Equal(t, []int64{14, 35}, p.Outputs())
+
+	p = NewPipeline()
+	p.AddNode(echo, 1)
+	_, err = p.Run()
+	assert.Equal(t, ErrNoOutput, err)
+}
+
+func Test_PipelineErrors(t *testing.T) {
+	echo := strings.Split(`3,0,4,0,99`, ",")
+
+	_, err := Chain(echo, nil, 0).Run()
+	assert.Equal(t, ErrNoPhases, err)
+	_, err = Loop(echo, []int64{}, 0).Run()
+	assert.Equal(t, ErrNoPhases, err)
+
+	for _, build := range []func(p *Pipeline, node int){
+		func(p *Pipeline, node int) { p.Input(node+1, 1) },
+		func(p *Pipeline, node int) { p.Connect(node, -1) },
+		func(p *Pipeline, node int) { p.Connect(node+1, node) },
+		func(p *Pipeline, node int) { p.Output(node + 1) },
+	} {
+		p := NewPipeline()
+		node := p.AddNode(echo, 1)
+		p.Output(node)
+		build(p, node)
+		_, err = p.Run()
+		assert.True(t, errors.Is(err, ErrNoNode), "%v", err)
+	}
+}
+
+func Test_SearchPhases(t *testing.T) {
+	chain := strings.Split(`3,15,3,16,1002,16,10,16,1,16,15,15,4,15,99,0,0`, ",")
+	run := func(phases []int64) (int64, error) {
+		return Chain(chain, phases, 0).Run()
+	}
+
+	phases, signal, err := SearchPhases([]int64{0, 1, 2, 3, 4}, Maximize, run)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{4, 3, 2, 1, 0}, phases)
+	assert.Equal(t, int64(43210), signal)
+
+	phases, signal, err = SearchPhases([]int64{0, 1, 2, 3, 4}, Minimize, run)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{0, 1, 2, 3, 4}, phases)
+	assert.Equal(t, int64(1234), signal)
+
+	phases, signal, err = SearchPhases([]int64{5, 6, 7, 8, 9}, Maximize, func(phases []int64) (int64, error) {
+		return Loop(strings.Split(feedbackProgram, ","), phases, 0).Run()
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{9, 8, 7, 6, 5}, phases)
+	assert.Equal(t, int64(139629729), signal)
+
+	_, _, err = SearchPhases([]int64{0, 1}, Maximize, func(phases []int64) (int64, error) {
+		return Chain(strings.Split(`3,0,99`, ","), phases, 0).Run()
+	})
+	assert.Equal(t, ErrNoOutput, err)
+
+	called := false
+	_, _, err = SearchPhases(nil, Maximize, func(phases []int64) (int64, error) {
+		called = true
+		return Chain(strings.Split(`3,0,4,0,99`, ","), phases, 0).Run()
+	})
+	assert.Equal(t, ErrNoPhases, err)
+	assert.False(t, called)
+
+	_, _, err = SearchPhases([]int64{0, 1}, Objective(2), run)
+	assert.EqualError(t, err, "unknown objective 2")
+
+	assert.Equal(t, 24, len(permutations(4)))
+	assert.Equal(t, []int{3, 2, 1, 0}, permutations(4)[23])
+}
@@ -0,0 +1,187 @@
+package intcode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Profiler counts what a computer executes.
+type Profiler struct {
+	c     *IntCodeComputer
+	hooks *Hooks
+
+	Instructions uint64
+	Inputs       uint64
+	Outputs      uint64
+	// OpCodes counts executions per opcode
+	OpCodes map[int]uint64
+	// Addresses counts executions per instruction address
+	Addresses map[int]uint64
+	// Reads and Writes count accesses per memory cell
+	Reads  map[int]uint64
+	Writes map[int]uint64
+}
+
+func NewProfiler(c *IntCodeComputer) *Profiler {
+	p := &Profiler{
+		c:         c,
+		OpCodes:   make(map[int]uint64),
+		Addresses: make(map[int]uint64),
+		Reads:     make(map[int]uint64),
+		Writes:    make(map[int]uint64),
+	}
+	p.hooks = &Hooks{AfterInstruction: p.afterInstruction}
+	c.AddHooks(p.hooks)
+	return p
+}
+
+// Detach removes the profiler's hooks from the computer.
+func (p *Profiler) Detach() {
+	p.c.RemoveHooks(p.hooks)
+}
+
+func (p *Profiler) afterInstruction(c *IntCodeComputer, e *Executed) {
+	p.Instructions++
+	p.OpCodes[e.OpCode]++
+	p.Addresses[e.Ptr]++
+	for _, addr := range e.Reads() {
+		p.Reads[addr]++
+	}
+	for _, w := range e.writes {
+		p.Writes[e.Addresses[w]]++
+	}
+	switch e.OpCode {
+	case 3:
+		p.Inputs++
+	case 4:
+		p.Outputs++
+	}
+}
+
+type count struct {
+	key int
+	n   uint64
+}
+
+// topCounts returns the n keys with the highest counts, highest first.
+func topCounts(counts map[int]uint64, n int) []count {
+	sorted := make([]count, 0, len(counts))
+	for k, v := range counts {
+		sorted = append(sorted, count{k, v})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].n != sorted[j].n {
+			return sorted[i].n > sorted[j].n
+		}
+		return sorted[i].key < sorted[j].key
+	})
+	if n > 0 && len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// Report writes a summary of the counts, listing the n busiest addresses and cells.
+func (p *Profiler) Report(w io.Writer, n int) error {
+	percent := func(v uint64) float64 {
+		if p.Instructions == 0 {
+			return 0
+		}
+		return float64(v) * 100 / float64(p.Instructions)
+	}
+
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	printf("instructions: %d, inputs: %d, outputs: %d\n", p.Instructions, p.Inputs, p.Outputs)
+
+	printf("\nopcodes:\n")
+	for _, c := range topCounts(p.OpCodes, 0) {
+		printf("  %-4s %12d %6.2f%%\n", p.c.mnemonic(c.key), c.n, percent(c.n))
+	}
+
+	printf("\nhottest addresses:\n")
+	for _, c := range topCounts(p.Addresses, n) {
+		inst, _ := p.c.DisassembleAt(c.key)
+		printf("  %6d %12d %6.2f%%  %s\n", c.key, c.n, percent(c.n), inst)
+	}
+
+	printf("\nmost read cells:\n")
+	for _, c := range topCounts(p.Reads, n) {
+		printf("  %6d %12d\n", c.key, c.n)
+	}
+
+	printf("\nmost written cells:\n")
+	for _, c := range topCounts(p.Writes, n) {
+		printf("  %6d %12d\n", c.key, c.n)
+	}
+
+	return err
+}
+
+// TraceRecord is one line of an instruction trace.
+type TraceRecord struct {
+	N        uint64   `json:"n"`
+	Ptr      int      `json:"ptr"`
+	Op       string   `json:"op"`
+	OpCode   int      `json:"opcode"`
+	Modes    []int    `json:"modes"`
+	Addr     []int    `json:"addr"`
+	Operands []string `json:"operands"`
+	Result   string   `json:"result,omitempty"`
+}
+
+// Tracer writes a TraceRecord as a JSON line for every instruction a computer executes.
+type Tracer struct {
+	c     *IntCodeComputer
+	hooks *Hooks
+	enc   *json.Encoder
+	err   error
+}
+
+// NewTracer attaches a tracer to c.  Records are written to w as they are executed, so w should be
+// buffered for long runs.
+func NewTracer(c *IntCodeComputer, w io.Writer) *Tracer {
+	t := &Tracer{c: c, enc: json.NewEncoder(w)}
+	t.hooks = &Hooks{AfterInstruction: t.afterInstruction}
+	c.AddHooks(t.hooks)
+	return t
+}
+
+// Detach removes the tracer's hooks from the computer.
+func (t *Tracer) Detach() {
+	t.c.RemoveHooks(t.hooks)
+}
+
+// Err returns the first error writing the trace.  Nothing more is written after an error.
+func (t *Tracer) Err() error {
+	return t.err
+}
+
+func (t *Tracer) afterInstruction(c *IntCodeComputer, e *Executed) {
+	if t.err != nil {
+		return
+	}
+	modes := make([]int, len(e.Addresses))
+	m := e.Instruction / 100
+	for i := range modes {
+		modes[i] = m % 10
+		m /= 10
+	}
+	t.err = t.enc.Encode(TraceRecord{
+		N:        c.instructions,
+		Ptr:      e.Ptr,
+		Op:       c.mnemonic(e.OpCode),
+		OpCode:   e.OpCode,
+		Modes:    modes,
+		Addr:     e.Addresses,
+		Operands: e.Operands,
+		Result:   e.Result,
+	})
+}
@@ -0,0 +1,66 @@
+package intcode
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_Profiler(t *testing.T) {
+	// counts down from the input, outputting each value
+	c := NewIntCodeComputer(strings.Split(`3,12,4,12,1001,12,-1,12,1005,12,2,99,0`, ","), nil, nil, nil, false, nil)
+	p := NewProfiler(c)
+
+	c.ProvideInput(3)
+	status, err := c.RunUntilInputNeeded()
+	assert.Nil(t, err)
+	assert.Equal(t, Halted, status)
+
+	assert.Equal(t, uint64(10), p.Instructions)
+	assert.Equal(t, uint64(1), p.Inputs)
+	assert.Equal(t, uint64(3), p.Outputs)
+	assert.Equal(t, map[int]uint64{3: 1, 4: 3, 1: 3, 5: 3}, p.OpCodes)
+	assert.Equal(t, uint64(3), p.Addresses[2])
+	assert.Equal(t, uint64(4), p.Writes[12])
+	// OUT, ADD and JNZ read the counter each time round, and the taken jumps read their target
+	assert.Equal(t, uint64(9), p.Reads[12])
+	assert.Equal(t, uint64(2), p.Reads[10])
+
+	var sb strings.Builder
+	assert.Nil(t, p.Report(&sb, 1))
+	assert.Contains(t, sb.String(), "instructions: 10, inputs: 1, outputs: 3\n")
+	assert.Contains(t, sb.String(), "\n       2            3  30.00%  OUT  [12]\n")
+
+	p.Detach()
+	c.Reset()
+	c.ProvideInput(1)
+	c.RunUntilInputNeeded()
+	assert.Equal(t, uint64(10), p.Instructions)
+}
+
+func Test_Tracer(t *testing.T) {
+	c := NewIntCodeComputer(strings.Split(`109,5,21101,2,3,0,1105,1,10,0,204,0,99`, ","), nil, nil, nil, false, nil)
+	var buf bytes.Buffer
+	tr := NewTracer(c, &buf)
+
+	status, err := c.RunUntilInputNeeded()
+	assert.Nil(t, err)
+	assert.Equal(t, Halted, status)
+	assert.Nil(t, tr.Err())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 4, len(lines))
+
+	var records []TraceRecord
+	for _, l := range lines {
+		var r TraceRecord
+		assert.Nil(t, json.Unmarshal([]byte(l), &r))
+		records = append(records, r)
+	}
+	assert.Equal(t, TraceRecord{N: 1, Ptr: 0, Op: "ARB", OpCode: 9, Modes: []int{1}, Addr: []int{1}, Operands: []string{"5"}, Result: "5"}, records[0])
+	assert.Equal(t, TraceRecord{N: 2, Ptr: 2, Op: "ADD", OpCode: 1, Modes: []int{1, 1, 2}, Addr: []int{3, 4, 5}, Operands: []string{"2", "3", "0"}, Result: "5"}, records[1])
+	assert.Equal(t, TraceRecord{N: 3, Ptr: 6, Op: "JNZ", OpCode: 5, Modes: []int{1, 1}, Addr: []int{7, 8}, Operands: []string{"1", "10"}, Result: "10"}, records[2])
+	assert.Equal(t, `{"n":4,"ptr":10,"op":"OUT","opcode":4,"modes":[2],"addr":[5],"operands":["5"],"result":"5"}`, lines[3])
+}
@@ -0,0 +1,195 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDeadlock is returned by Scheduler.Run when every program that hasn't stopped is waiting for input
+// and none was provided.
+var ErrDeadlock = errors.New("all programs are waiting for input")
+
+// DefaultSlice is the number of instructions a Scheduler runs a program for before switching to the next.
+const DefaultSlice = 1000
+
+// Scheduler runs many computers round robin on one goroutine.  Each computer runs until it produces an
+// output, needs input, yields, or has executed a slice of instructions, and then the next one gets a
+// turn.  There are no goroutines or timers involved, so the same programs and inputs always interleave
+// the same way.
+type Scheduler struct {
+	// Slice is the most instructions a computer runs for in one turn
+	Slice uint64
+	// OnRound is called after every computer has had a turn, if it is set
+	OnRound func(s *Scheduler)
+
+	tasks   []*Task
+	current *Task
+	round   uint64
+	stopped bool
+}
+
+// Task is a computer run by a Scheduler.
+type Task struct {
+	ID       int // the order the task was added in, starting at 0
+	Computer *IntCodeComputer
+
+	onInput  func(t *Task)
+	onOutput func(t *Task, value int64)
+	status   Status
+	err      error
+	blocked  bool
+}
+
+// NewScheduler creates a scheduler that switches computers at least every slice instructions, or every
+// DefaultSlice if slice is 0.
+func NewScheduler(slice uint64) *Scheduler {
+	if slice == 0 {
+		slice = DefaultSlice
+	}
+	return &Scheduler{Slice: slice}
+}
+
+// Add schedules c.  onInput is called when the program needs input and none is queued; it can provide
+// some with Send, or leave the task blocked until another task sends it a value.  onOutput is called with
+// each value the program outputs, which is also available from c.OutputText.  Either may be nil.
+func (s *Scheduler) Add(c *IntCodeComputer, onInput func(t *Task), onOutput func(t *Task, value int64)) *Task {
+	t := &Task{ID: len(s.tasks), Computer: c, onInput: onInput, onOutput: onOutput, status: Running}
+	s.tasks = append(s.tasks, t)
+	return t
+}
+
+// Tasks returns the scheduled tasks in the order they were added.
+func (s *Scheduler) Tasks() []*Task {
+	return s.tasks
+}
+
+// Round returns the number of completed rounds, in which every running task had a turn.  It serves as a
+// logical clock.
+func (s *Scheduler) Round() uint64 {
+	return s.round
+}
+
+// Current returns the task having its turn, or nil between rounds.
+func (s *Scheduler) Current() *Task {
+	return s.current
+}
+
+// Stop makes Run return after the current turn.  It is meant to be called from the callbacks.
+func (s *Scheduler) Stop() {
+	s.stopped = true
+}
+
+// Run gives the tasks turns until they have all halted or faulted, or Stop is called.  It returns the
+// first fault, ErrDeadlock if the remaining tasks all wait for input that nothing will provide, or
+// ErrPaused if a hook paused a computer.  Run can be called again to continue.
+func (s *Scheduler) Run() error {
+	s.stopped = false
+	var fault error
+	for {
+		live := 0
+		for _, t := range s.tasks {
+			if t.Done() {
+				continue
+			}
+			s.current = t
+			s.turn(t)
+			s.current = nil
+			switch {
+			case t.status == Faulted:
+				if fault == nil {
+					fault = fmt.Errorf("task %d: %w", t.ID, t.err)
+				}
+			case t.status == Paused:
+				return ErrPaused
+			case t.status != Halted:
+				live++
+			}
+			if s.stopped {
+				return fault
+			}
+		}
+		s.round++
+		if s.OnRound != nil {
+			s.OnRound(s)
+			if s.stopped {
+				return fault
+			}
+		}
+		if live == 0 {
+			return fault
+		}
+		if s.allBlocked() {
+			if fault != nil {
+				return fault
+			}
+			return ErrDeadlock
+		}
+	}
+}
+
+// allBlocked reports whether every running task is still waiting for input after the round.
+func (s *Scheduler) allBlocked() bool {
+	for _, t := range s.tasks {
+		if !t.Done() && (!t.blocked || len(t.Computer.input) > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// turn runs t until it switches or has run for a slice.
+func (s *Scheduler) turn(t *Task) {
+	c := t.Computer
+	for n := uint64(0); n < s.Slice; n++ {
+		if c.budget > 0 && c.instructions >= c.budget {
+			c.err = ErrBudgetExhausted
+			t.status, t.err = Faulted, c.err
+			return
+		}
+		status, err := c.Step()
+		t.status, t.err = status, err
+		switch status {
+		case Running:
+			t.blocked = false
+			continue
+		case ProducedOutput:
+			t.blocked = false
+			if t.onOutput != nil {
+				t.onOutput(t, c.Output())
+			}
+		case NeedsInput:
+			if t.onInput != nil {
+				t.onInput(t)
+			}
+			t.blocked = len(c.input) == 0
+		case Yielded:
+			t.blocked = false
+		}
+		return
+	}
+}
+
+// Send queues values for the task's input instructions.
+func (t *Task) Send(values ...int64) {
+	t.Computer.ProvideInput(values...)
+}
+
+// Status returns the status the task's computer stopped with at the end of its last turn.
+func (t *Task) Status() Status {
+	return t.status
+}
+
+// Err returns the fault that stopped the task, if any.
+func (t *Task) Err() error {
+	return t.err
+}
+
+// Done reports whether the task's program halted or faulted.
+func (t *Task) Done() bool {
+	return t.status == Halted || t.status == Faulted
+}
+
+// Blocked reports whether the task is waiting for input that hasn't been provided.
+func (t *Task) Blocked() bool {
+	return t.blocked && len(t.Computer.input) == 0
+}
@@ -0,0 +1,102 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// day7's feedback loop example, whose best phase settings 9,8,7,6,5 give 139629729
+const feedbackProgram = `3,26,1001,26,-4,26,3,27,1002,27,2,27,1,27,26,27,4,27,1001,28,-1,28,1005,28,6,99,0,0,5`
+
+func Test_SchedulerFeedbackLoop(t *testing.T) {
+	run := func(slice uint64) (int64, []string) {
+		s := NewScheduler(slice)
+		var tasks []*Task
+		var last int64
+		var trace []string
+		for _, phase := range []int64{9, 8, 7, 6, 5} {
+			c := NewIntCodeComputer(strings.Split(feedbackProgram, ","), nil, nil, nil, false, nil)
+			task := s.Add(c, nil, func(t *Task, value int64) {
+				trace = append(trace, fmt.Sprintf("%d:%d", t.ID, value))
+				last = value
+				tasks[(t.ID+1)%len(tasks)].Send(value)
+			})
+			task.Send(phase)
+			tasks = append(tasks, task)
+		}
+		tasks[0].Send(0)
+		assert.Nil(t, s.Run())
+		return last, trace
+	}
+
+	last, trace := run(0)
+	assert.Equal(t, int64(139629729), last)
+	assert.Equal(t, 25, len(trace))
+
+	// the interleaving is the same every time
+	for i := 0; i < 3; i++ {
+		again, againTrace := run(0)
+		assert.Equal(t, last, again)
+		assert.Equal(t, trace, againTrace)
+	}
+	last, _ = run(1)
+	assert.Equal(t, int64(139629729), last)
+}
+
+func Test_SchedulerSlice(t *testing.T) {
+	s := NewScheduler(3)
+	loop := strings.Split(`1105,1,0`, ",")
+	a := s.Add(NewIntCodeComputer(loop, nil, nil, nil, false, nil), nil, nil)
+	b := s.Add(NewIntCodeComputer(loop, nil, nil, nil, false, nil), nil, nil)
+	s.OnRound = func(s *Scheduler) {
+		if s.Round() == 4 {
+			s.Stop()
+		}
+	}
+	assert.Nil(t, s.Run())
+	assert.Equal(t, uint64(4), s.Round())
+	assert.Equal(t, uint64(12), a.Computer.Instructions())
+	assert.Equal(t, uint64(12), b.Computer.Instructions())
+	assert.Equal(t, Running, a.Status())
+}
+
+func Test_SchedulerDeadlock(t *testing.T) {
+	s := NewScheduler(0)
+	program := strings.Split(`3,0,4,0,99`, ",")
+	var outputs []int64
+	output := func(t *Task, value int64) {
+		outputs = append(outputs, value)
+	}
+	a := s.Add(NewIntCodeComputer(program, nil, nil, nil, false, nil), nil, output)
+	b := s.Add(NewIntCodeComputer(program, nil, nil, nil, false, nil), func(t *Task) {
+		t.Send(2)
+	}, output)
+	c := s.Add(NewIntCodeComputer(program, nil, nil, nil, false, nil), nil, output)
+
+	assert.Equal(t, ErrDeadlock, s.Run())
+	assert.True(t, a.Blocked())
+	assert.True(t, b.Done())
+	assert.Equal(t, []int64{2}, outputs)
+
+	a.Send(1)
+	c.Send(3)
+	assert.False(t, a.Blocked())
+	assert.Nil(t, s.Run())
+	assert.Equal(t, []int64{2, 1, 3}, outputs)
+	assert.Equal(t, Halted, c.Status())
+}
+
+func Test_SchedulerFault(t *testing.T) {
+	s := NewScheduler(0)
+	s.Add(NewIntCodeComputer(strings.Split(`104,1,104,2,99`, ","), nil, nil, nil, false, nil), nil, nil)
+	bad := s.Add(NewIntCodeComputer(strings.Split(`104,1,25,0`, ","), nil, nil, nil, false, nil), nil, nil)
+
+	err := s.Run()
+	assert.True(t, errors.Is(err, ErrInvalidOpcode))
+	assert.True(t, strings.HasPrefix(err.Error(), "task 1: "))
+	assert.Equal(t, Faulted, bad.Status())
+	assert.Equal(t, Halted, s.Tasks()[0].Status())
+}
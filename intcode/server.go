@@ -0,0 +1,283 @@
+package intcode
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrServerClosed is returned by Server.Serve after Close is called.
+var ErrServerClosed = errors.New("server closed")
+
+const serverHelp = `commands:
+  !snapshot <name>  save the session on the server
+  !restore <name>   restore a saved session
+  !quit             end the session
+  !help             show this help
+other lines are sent to the program as ASCII input`
+
+// maxLineLength is the longest line a client can send.  Longer lines end the session.
+const maxLineLength = 64 << 10
+
+// snapshotName limits snapshot names to ones that are safe to use as file names.
+var snapshotName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Server runs an ASCII capable program for each client that connects, such as day25's adventure.  The
+// protocol is line based: lines the client sends are the program's input, and the program's output is
+// sent back as it is produced, with values that aren't ASCII on lines of their own.  Lines starting with
+// ! are commands for the server; !help lists them.  The connection is closed when the program halts or
+// faults.
+type Server struct {
+	// IdleTimeout ends sessions that send nothing for this long, if it is not 0
+	IdleTimeout time.Duration
+	// SnapshotDir is where the !snapshot command saves sessions.  Snapshots are disabled if it is empty.
+	SnapshotDir string
+	// Budget is the most instructions a session runs for each line of input, if it is not 0, so a
+	// program that loops forever can't keep the server busy
+	Budget uint64
+	// WriteTimeout ends sessions whose client stops reading for this long, if it is not 0.  NewServer
+	// sets it to a minute.
+	WriteTimeout time.Duration
+
+	program *IntCodeComputer
+	mu      sync.Mutex
+	ln      net.Listener
+	conns   map[net.Conn]bool
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+func NewServer(program []string) *Server {
+	return &Server{
+		WriteTimeout: time.Minute,
+		program:      NewIntCodeComputer(program, nil, nil, nil, false, nil),
+		conns:        make(map[net.Conn]bool),
+	}
+}
+
+// ListenAndServe listens on the TCP address addr, such as localhost:2019, and serves connections.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln until Close is called, starting a session for each.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ln.Close()
+		return ErrServerClosed
+	}
+	s.ln = ln
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = true
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		// cloning changes the program's memory, so it isn't done by the sessions
+		c := s.program.Clone()
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(conn, c)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// Addr returns the address the server is listening on, or nil if it isn't.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+// Close stops accepting connections, ends every session and waits for them to finish.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.ln != nil {
+		err = s.ln.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+type session struct {
+	s       *Server
+	w       *bufio.Writer
+	ascii   *ASCII
+	midLine bool // the program's output ended part way through a line
+}
+
+// deadlineWriter sets a write deadline before each write, so a client that stops reading can't block
+// its session forever.
+type deadlineWriter struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (w deadlineWriter) Write(p []byte) (int, error) {
+	if w.timeout > 0 {
+		w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	}
+	return w.conn.Write(p)
+}
+
+func (s *Server) serveConn(conn net.Conn, c *IntCodeComputer) {
+	sess := &session{
+		s:     s,
+		w:     bufio.NewWriter(deadlineWriter{conn: conn, timeout: s.WriteTimeout}),
+		ascii: NewASCII(c),
+	}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineLength)
+	for sess.run() {
+		if s.IdleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.IdleTimeout))
+		}
+		if !scanner.Scan() {
+			if e, ok := scanner.Err().(net.Error); ok && e.Timeout() {
+				sess.printf("idle timeout\n")
+			} else if scanner.Err() == bufio.ErrTooLong {
+				sess.printf("line too long\n")
+			}
+			return
+		}
+		// the client ended the line when it sent its own
+		sess.midLine = false
+		line := scanner.Text()
+		if strings.HasPrefix(line, "!") {
+			if !sess.command(line[1:]) {
+				return
+			}
+			continue
+		}
+		sess.ascii.WriteLine(line)
+	}
+}
+
+// run runs the program until it needs input, sending its output to the client.  It returns false once
+// the session is over.
+func (sess *session) run() bool {
+	c := sess.ascii.Computer()
+	if sess.s.Budget > 0 {
+		c.SetInstructionBudget(c.Instructions() + sess.s.Budget)
+	}
+	for {
+		text, err := sess.ascii.ReadUntilInput()
+		sess.write(text)
+		if out, ok := err.(*NonASCIIOutput); ok {
+			sess.printf("%s\n", out.Text)
+			continue
+		}
+		switch err {
+		case ErrInputNeeded:
+			return sess.w.Flush() == nil
+		case io.EOF:
+		default:
+			sess.printf("error: %v\n", err)
+		}
+		sess.w.Flush()
+		return false
+	}
+}
+
+// write sends the program's output to the client.
+func (sess *session) write(text string) {
+	if text != "" {
+		sess.w.WriteString(text)
+		sess.midLine = text[len(text)-1] != '\n'
+	}
+}
+
+// printf sends a line of its own to the client, ending the program's output line first if it has to.
+func (sess *session) printf(format string, args ...interface{}) {
+	if sess.midLine {
+		sess.w.WriteByte('\n')
+		sess.midLine = false
+	}
+	fmt.Fprintf(sess.w, format, args...)
+	sess.w.Flush()
+}
+
+// command runs a server command.  It returns false if the session should end.
+func (sess *session) command(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		fields = []string{"help"}
+	}
+	switch fields[0] {
+	case "quit":
+		return false
+	case "help":
+		sess.printf("%s\n", serverHelp)
+	case "snapshot", "restore":
+		if sess.s.SnapshotDir == "" {
+			sess.printf("snapshots are disabled\n")
+			return true
+		}
+		if len(fields) != 2 || !snapshotName.MatchString(fields[1]) {
+			sess.printf("usage: !%s <name>, using letters, digits, _ and -\n", fields[0])
+			return true
+		}
+		filename := filepath.Join(sess.s.SnapshotDir, fields[1]+".json")
+		c := sess.ascii.Computer()
+		if fields[0] == "snapshot" {
+			if err := c.Save(filename); err != nil {
+				sess.printf("error: %v\n", err)
+				return true
+			}
+			sess.printf("saved %s\n", fields[1])
+			return true
+		}
+		if err := c.Load(filename); err != nil {
+			sess.printf("error: %v\n", err)
+			return true
+		}
+		sess.ascii = NewASCII(c)
+		sess.printf("restored %s\n", fields[1])
+	default:
+		sess.printf("unknown command !%s\n", fields[0])
+	}
+	return true
+}
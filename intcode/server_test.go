@@ -0,0 +1,151 @@
+package intcode
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoes its input until it reads a q, then outputs 12345 and halts
+const asmEcho = `
+loop:   IN   [c]
+        EQ   [c], #113, [t]
+        JNZ  [t], #done
+        OUT  [c]
+        JZ   #0, #loop
+done:   OUT  #12345
+        HLT
+c:      DATA 0
+t:      DATA 0
+`
+
+func startServer(t *testing.T, configure func(s *Server)) (*Server, string, chan error) {
+	assembled, err := Assemble(asmEcho)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	s := NewServer(assembled.Program)
+	if configure != nil {
+		configure(s)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ln) }()
+	return s, ln.Addr().String(), done
+}
+
+type testClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, addr string) *testClient {
+	conn, err := net.Dial("tcp", addr)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &testClient{conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *testClient) send(line string) {
+	io.WriteString(c.conn, line+"\n")
+}
+
+func (c *testClient) line() string {
+	line, _ := c.r.ReadString('\n')
+	return strings.TrimSuffix(line, "\n")
+}
+
+func Test_Server(t *testing.T) {
+	dir, err := ioutil.TempDir("", "intcode")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	s, addr, done := startServer(t, func(s *Server) {
+		s.SnapshotDir = dir
+	})
+
+	a := dial(t, addr)
+	b := dial(t, addr)
+	a.send("hello")
+	b.send("other")
+	assert.Equal(t, "hello", a.line())
+	assert.Equal(t, "other", b.line())
+
+	a.send("!snapshot one")
+	assert.Equal(t, "saved one", a.line())
+	a.send("!snapshot ../one")
+	assert.True(t, strings.HasPrefix(a.line(), "usage: !snapshot"))
+	a.send("!restore one")
+	assert.Equal(t, "restored one", a.line())
+	a.send("!bogus")
+	assert.Equal(t, "unknown command !bogus", a.line())
+
+	a.send("abq")
+	assert.Equal(t, "ab", a.line())
+	assert.Equal(t, "12345", a.line())
+	_, err = a.r.ReadString('\n')
+	assert.Equal(t, io.EOF, err)
+
+	b.send("!quit")
+	_, err = b.r.ReadString('\n')
+	assert.Equal(t, io.EOF, err)
+
+	assert.Nil(t, s.Close())
+	assert.Equal(t, ErrServerClosed, <-done)
+}
+
+func Test_ServerIdleTimeout(t *testing.T) {
+	s, addr, done := startServer(t, func(s *Server) {
+		s.IdleTimeout = 50 * time.Millisecond
+	})
+
+	a := dial(t, addr)
+	a.send("ab")
+	assert.Equal(t, "ab", a.line())
+	assert.Equal(t, "idle timeout", a.line())
+	_, err := a.r.ReadString('\n')
+	assert.Equal(t, io.EOF, err)
+
+	assert.Nil(t, s.Close())
+	assert.Equal(t, ErrServerClosed, <-done)
+}
+
+func Test_ServerLimits(t *testing.T) {
+	s, addr, done := startServer(t, func(s *Server) {
+		s.Budget = 100
+	})
+
+	b := dial(t, addr)
+	b.send("!snapshot one")
+	assert.Equal(t, "snapshots are disabled", b.line())
+	b.send(strings.Repeat("x", 100))
+	assert.Equal(t, strings.Repeat("x", 20), b.line())
+	assert.Equal(t, "error: instruction budget exhausted", b.line())
+
+	long := dial(t, addr)
+	long.send(strings.Repeat("x", maxLineLength+1))
+	assert.Equal(t, "line too long", long.line())
+	// the rest of the line isn't read, so the connection may be reset rather than closed
+	_, err := long.r.ReadString('\n')
+	assert.NotNil(t, err)
+
+	// closing the server ends open sessions
+	c := dial(t, addr)
+	c.send("hi")
+	assert.Equal(t, "hi", c.line())
+	assert.Nil(t, s.Close())
+	_, err = c.r.ReadString('\n')
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, ErrServerClosed, <-done)
+}
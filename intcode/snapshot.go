@@ -0,0 +1,276 @@
+package intcode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+)
+
+// SnapshotVersion is the version of the snapshot format written by Save.  Version 3 stores memory as
+// runs of cells instead of one cell per address.
+const SnapshotVersion = 3
+
+var (
+	ErrSnapshotVersion  = errors.New("unsupported snapshot version")
+	ErrSnapshotChecksum = errors.New("snapshot checksum mismatch")
+)
+
+// Snapshot is the full state of a computer.  Memory holds the cells that aren't zero, and Length is one
+// past the highest address that has been written.
+type Snapshot struct {
+	Ptr            int         `json:"ptr"`
+	RelativeBase   int         `json:"relativeBase"`
+	Length         int         `json:"length"`
+	Memory         []MemoryRun `json:"memory"`
+	Original       []string    `json:"original,omitempty"`
+	BigMode        bool        `json:"bigMode"`
+	LastOut        string      `json:"lastOut"`
+	Output         int64       `json:"output"`
+	Input          []string    `json:"input,omitempty"`
+	Outputs        []int64     `json:"outputs,omitempty"`
+	InputPrompt    *string     `json:"inputPrompt,omitempty"`
+	Instructions   uint64      `json:"instructions"`
+	InstructionPtr int         `json:"instructionPtr"`
+}
+
+// denseSnapshot is the snapshot format before version 3, which had a cell for every address.
+type denseSnapshot struct {
+	Snapshot
+	Memory []string `json:"memory"`
+}
+
+func (d *denseSnapshot) sparse() *Snapshot {
+	s := d.Snapshot
+	s.Length = len(d.Memory)
+	s.Memory = []MemoryRun{{Addr: 0, Values: d.Memory}}
+	return &s
+}
+
+// snapshotFile is the envelope a snapshot is stored in.  State holds the snapshot as JSON, or Data holds
+// it gzipped when the snapshot is compressed.  Checksum is the SHA-256 of the compact snapshot JSON.
+type snapshotFile struct {
+	Version    int             `json:"version"`
+	Compressed bool            `json:"compressed,omitempty"`
+	Checksum   string          `json:"checksum"`
+	State      json.RawMessage `json:"state,omitempty"`
+	Data       []byte          `json:"data,omitempty"`
+}
+
+// Snapshot returns the computer's state.
+func (c *IntCodeComputer) Snapshot() *Snapshot {
+	s := &Snapshot{
+		Ptr:            c.ptr,
+		RelativeBase:   c.relativeBase,
+		Length:         c.memory.length,
+		Memory:         c.MemoryRuns(),
+		BigMode:        c.bigMode,
+		LastOut:        c.lastOut,
+		Output:         c.output,
+		Input:          append([]string(nil), c.input...),
+		Outputs:        append([]int64(nil), c.outputs...),
+		Instructions:   c.instructions,
+		InstructionPtr: c.instructionPtr,
+	}
+	if c.origErr == nil {
+		s.Original = formatProgram(c.origMemory, c.origWide)
+	}
+	if c.inputPrompt != nil {
+		prompt := *c.inputPrompt
+		s.InputPrompt = &prompt
+	}
+	return s
+}
+
+// Restore sets the computer's state from a snapshot.  The computer is left unchanged if the snapshot is
+// invalid.
+func (c *IntCodeComputer) Restore(s *Snapshot) error {
+	memory, wide, err := parseMemoryRuns(s.Memory, s.Length)
+	if err != nil {
+		return err
+	}
+	var origMemory []int64
+	var origWide map[int]*big.Int
+	if s.Original != nil {
+		if origMemory, origWide, err = parseProgram(s.Original); err != nil {
+			return err
+		}
+	}
+
+	c.memory = memory
+	c.wide = wide
+	c.bigMode = s.BigMode || len(wide) > 0
+	if s.Original != nil {
+		c.origMemory, c.origWide, c.origErr = origMemory, origWide, nil
+		c.origCode = nil
+	}
+	c.ptr = s.Ptr
+	c.relativeBase = s.RelativeBase
+	c.lastOut = s.LastOut
+	c.output = s.Output
+	c.input = append([]string(nil), s.Input...)
+	c.outputs = append([]int64(nil), s.Outputs...)
+	if s.InputPrompt != nil {
+		prompt := *s.InputPrompt
+		c.inputPrompt = &prompt
+	}
+	c.instructions = s.Instructions
+	c.instructionPtr = s.InstructionPtr
+	c.progErr = nil
+	c.err = nil
+	return nil
+}
+
+// WriteSnapshot writes the computer's state to w, gzipped if compress is true.
+func (c *IntCodeComputer) WriteSnapshot(w io.Writer, compress bool) error {
+	state, err := json.Marshal(c.Snapshot())
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(state)
+	f := snapshotFile{
+		Version:    SnapshotVersion,
+		Compressed: compress,
+		Checksum:   hex.EncodeToString(sum[:]),
+	}
+	if compress {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(state); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		f.Data = buf.Bytes()
+	} else {
+		f.State = state
+	}
+	return json.NewEncoder(w).Encode(f)
+}
+
+// ReadSnapshot restores the computer's state from r.  It also reads the Memory format written before
+// snapshots were versioned.
+func (c *IntCodeComputer) ReadSnapshot(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var f snapshotFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	if f.Version == 0 {
+		var mem Memory
+		if err := json.Unmarshal(data, &mem); err != nil {
+			return err
+		}
+		if mem.Program == nil {
+			return fmt.Errorf("snapshot has no program")
+		}
+		d := &denseSnapshot{
+			Snapshot: Snapshot{
+				Ptr:          mem.Ptr,
+				BigMode:      c.bigMode,
+				RelativeBase: mem.RelativeBase,
+				LastOut:      mem.LastOut,
+				InputPrompt:  c.inputPrompt,
+			},
+			Memory: mem.Program,
+		}
+		return c.Restore(d.sparse())
+	}
+	if f.Version > SnapshotVersion {
+		return fmt.Errorf("%w %d", ErrSnapshotVersion, f.Version)
+	}
+
+	state := []byte(f.State)
+	if f.Compressed {
+		gz, err := gzip.NewReader(bytes.NewReader(f.Data))
+		if err != nil {
+			return err
+		}
+		if state, err = ioutil.ReadAll(gz); err != nil {
+			return err
+		}
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, state); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(compact.Bytes())
+	if hex.EncodeToString(sum[:]) != f.Checksum {
+		return ErrSnapshotChecksum
+	}
+
+	if f.Version < 3 {
+		var d denseSnapshot
+		if err := json.Unmarshal(state, &d); err != nil {
+			return err
+		}
+		return c.Restore(d.sparse())
+	}
+	var s Snapshot
+	if err := json.Unmarshal(state, &s); err != nil {
+		return err
+	}
+	return c.Restore(&s)
+}
+
+// Save writes the computer's state to filename.  The file is replaced atomically, so an existing
+// snapshot is kept if saving fails.
+func (c *IntCodeComputer) Save(filename string) error {
+	return c.save(filename, false)
+}
+
+// SaveCompressed is like Save, but gzips the state.
+func (c *IntCodeComputer) SaveCompressed(filename string) error {
+	return c.save(filename, true)
+}
+
+func (c *IntCodeComputer) save(filename string, compress bool) error {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	file, err := ioutil.TempFile(dir, base+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := c.WriteSnapshot(file, compress); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filename)
+}
+
+// Load restores the computer's state from a file written by Save or SaveCompressed, or from an older
+// Memory file.
+func (c *IntCodeComputer) Load(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return c.ReadSnapshot(file)
+}
@@ -0,0 +1,143 @@
+package intcode
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// echoes every input doubled, forever
+const snapshotProgram = `3,11,1002,11,2,11,4,11,1105,1,0,0`
+
+func Test_SnapshotRoundTrip(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		prompt := "input:"
+		c := NewIntCodeComputer(strings.Split(snapshotProgram, ","), nil, nil, nil, false, &prompt)
+		c.ProvideInput(1, 2, 3)
+		c.RunUntilOutput()
+		c.RunUntilOutput()
+
+		var buf bytes.Buffer
+		assert.Nil(t, c.WriteSnapshot(&buf, compress))
+		assert.Equal(t, compress, !strings.Contains(buf.String(), `"memory"`))
+
+		r := NewIntCodeComputer([]string{"99"}, nil, nil, nil, false, nil)
+		assert.Nil(t, r.ReadSnapshot(&buf))
+		assert.Equal(t, c.Registers(), r.Registers())
+		assert.Equal(t, "input:", *r.inputPrompt)
+
+		status, err := r.RunUntilInputNeeded()
+		assert.Nil(t, err)
+		assert.Equal(t, NeedsInput, status)
+		assert.Equal(t, []int64{6}, r.TakeOutputs())
+
+		// resetting goes back to the original program, not the one r was created with
+		r.Reset()
+		r.ProvideInput(5)
+		r.RunUntilOutput()
+		assert.Equal(t, int64(10), r.Output())
+	}
+}
+
+func Test_SnapshotErrors(t *testing.T) {
+	c := NewIntCodeComputer(strings.Split(snapshotProgram, ","), nil, nil, nil, false, nil)
+	var buf bytes.Buffer
+	assert.Nil(t, c.WriteSnapshot(&buf, false))
+
+	tampered := strings.Replace(buf.String(), `"ptr":0`, `"ptr":4`, 1)
+	r := NewIntCodeComputer([]string{"99"}, nil, nil, nil, false, nil)
+	assert.Equal(t, ErrSnapshotChecksum, r.ReadSnapshot(strings.NewReader(tampered)))
+	assert.Equal(t, []string{"99"}, programOf(r))
+
+	newer := strings.Replace(buf.String(), `"version":3`, `"version":99`, 1)
+	assert.True(t, errors.Is(r.ReadSnapshot(strings.NewReader(newer)), ErrSnapshotVersion))
+
+	assert.NotNil(t, r.ReadSnapshot(strings.NewReader("not json")))
+	assert.NotNil(t, r.ReadSnapshot(strings.NewReader(`{"ptr":1}`)))
+
+	// memory runs must fit in the memory's length
+	assert.NotNil(t, r.Restore(&Snapshot{Length: 2, Memory: []MemoryRun{{Addr: 1, Values: []string{"1", "2"}}}}))
+	assert.NotNil(t, r.Restore(&Snapshot{Length: 2, Memory: []MemoryRun{{Addr: -1, Values: []string{"1"}}}}))
+	assert.True(t, errors.Is(r.Restore(&Snapshot{Length: 2, Memory: []MemoryRun{{Addr: 0, Values: []string{"x"}}}}), ErrUnparsableCell))
+	assert.Equal(t, []string{"99"}, programOf(r))
+}
+
+func Test_SnapshotSparse(t *testing.T) {
+	c := NewIntCodeComputer(strings.Split(`1101,42,0,1000000000,104,100000000000000000000,99`, ","), nil, nil, nil, false, nil)
+	_, err := c.RunUntilInputNeeded()
+	assert.Nil(t, err)
+	_, err = c.GetProgram()
+	assert.True(t, errors.Is(err, ErrSparseMemory))
+	assert.Equal(t, []MemoryRun{
+		{Addr: 0, Values: []string{"1101", "42", "0", "1000000000", "104", "100000000000000000000", "99"}},
+		{Addr: 1000000000, Values: []string{"42"}},
+	}, c.MemoryRuns())
+
+	var buf bytes.Buffer
+	assert.Nil(t, c.WriteSnapshot(&buf, false))
+	assert.True(t, buf.Len() < 1000)
+
+	r := NewIntCodeComputer([]string{"99"}, nil, nil, nil, false, nil)
+	assert.Nil(t, r.ReadSnapshot(&buf))
+	assert.Equal(t, c.MemoryStats(), r.MemoryStats())
+	assert.Equal(t, int64(42), r.Peek(1000000000))
+	assert.Equal(t, "100000000000000000000", r.PeekText(5))
+	assert.Equal(t, c.Registers(), r.Registers())
+}
+
+func Test_SnapshotVersion2(t *testing.T) {
+	// version 2 snapshots had a cell for every address
+	d := denseSnapshot{Snapshot: Snapshot{Ptr: 2}, Memory: strings.Split(snapshotProgram, ",")}
+	d.Memory[11] = "8"
+	state, err := json.Marshal(d)
+	assert.Nil(t, err)
+	sum := sha256.Sum256(state)
+	f, err := json.Marshal(snapshotFile{Version: 2, Checksum: hex.EncodeToString(sum[:]), State: state})
+	assert.Nil(t, err)
+
+	r := NewIntCodeComputer([]string{"99"}, nil, nil, nil, false, nil)
+	assert.Nil(t, r.ReadSnapshot(bytes.NewReader(f)))
+	assert.Equal(t, 12, r.MemoryStats().Length)
+	r.RunUntilOutput()
+	assert.Equal(t, int64(16), r.Output())
+}
+
+func Test_SnapshotFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	c := NewIntCodeComputer(strings.Split(snapshotProgram, ","), nil, nil, nil, false, nil)
+	c.ProvideInput(21)
+	c.RunUntilOutput()
+
+	filename := filepath.Join(dir, "game.json")
+	assert.Nil(t, c.Save(filename))
+	assert.Nil(t, c.SaveCompressed(filename))
+	files, _ := ioutil.ReadDir(dir)
+	assert.Equal(t, 1, len(files))
+
+	r := NewIntCodeComputer([]string{"99"}, nil, nil, nil, false, nil)
+	assert.Nil(t, r.Load(filename))
+	assert.Equal(t, int64(42), r.Output())
+
+	assert.NotNil(t, c.Save(filepath.Join(dir, "missing", "game.json")))
+	assert.NotNil(t, r.Load(filepath.Join(dir, "missing.json")))
+
+	// the format written before snapshots were versioned
+	legacy := filepath.Join(dir, "legacy.json")
+	assert.Nil(t, ioutil.WriteFile(legacy, []byte(`{"ptr":2,"program":["3","11","1002","11","2","11","4","11","1105","1","0","8"],"relativeBase":0,"lastOut":"16"}`), 0644))
+	assert.Nil(t, r.Load(legacy))
+	status, _ := r.RunUntilOutput()
+	assert.Equal(t, ProducedOutput, status)
+	assert.Equal(t, int64(16), r.Output())
+	assert.Equal(t, "16", r.Registers().LastOut)
+}
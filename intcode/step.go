@@ -0,0 +1,288 @@
+package intcode
+
+import (
+	"strconv"
+)
+
+// Status describes where the computer stopped after a step.
+type Status int
+
+const (
+	Running        Status = iota // an instruction was executed and the program can continue
+	NeedsInput                   // the program is waiting on an input instruction
+	ProducedOutput               // an output instruction was executed
+	Halted                       // the program halted
+	Faulted                      // the program faulted or ran out of instruction budget
+	Paused                       // a hook paused the computer before an instruction
+	Yielded                      // an extension instruction gave up control and the program can continue
+)
+
+func (s Status) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case NeedsInput:
+		return "needs input"
+	case ProducedOutput:
+		return "produced output"
+	case Halted:
+		return "halted"
+	case Faulted:
+		return "faulted"
+	case Paused:
+		return "paused"
+	case Yielded:
+		return "yielded"
+	}
+	return "unknown"
+}
+
+// step executes the instruction at ptr.  An input instruction with no queued input is not executed
+// and leaves ptr where it is, so it can be retried once input is provided.
+func (c *IntCodeComputer) step() (Status, error) {
+	if c.progErr != nil {
+		return Faulted, c.progErr
+	}
+	if c.ptr >= c.memory.length {
+		return Halted, nil
+	}
+	if c.compiled && c.hooks == nil {
+		if status, err, ok := c.stepCompiled(); ok {
+			return status, err
+		}
+	}
+	if c.ptr < 0 {
+		return Faulted, c.newFault(ErrNegativeAddress, "instruction pointer is negative")
+	}
+	opCode, err := c.getAddress(c.ptr)
+	if err != nil {
+		return Faulted, c.newFault(ErrInvalidOpcode, "opcode does not fit in an int")
+	}
+	length, ok := c.opCodes[opCode%100]
+	if !ok || opCode < 0 {
+		return Faulted, c.newFault(ErrInvalidOpcode, "")
+	}
+
+	// an input instruction waiting for input isn't about to run, so hooks only see it once it can
+	if opCode%100 == 3 && len(c.input) == 0 {
+		return NeedsInput, nil
+	}
+
+	if c.hooks != nil && !c.beforeInstruction(opCode) {
+		return Paused, nil
+	}
+
+	tmp := opCode
+	opCode = tmp % 100
+	tmp /= 100
+
+	if opCode == 99 {
+		return Halted, nil
+	}
+
+	l := length - 1
+
+	// parameter address modes
+	modes := make([]int, l, l)
+	// op code paramPositions
+	paramPositions := make([]int, l, l)
+
+	for j := 1; j < length; j++ {
+		modes[j-1] = tmp % 10
+
+		switch modes[j-1] {
+		case 0:
+			// position mode
+			pos, err := c.getAddress(c.ptr + j)
+			if err != nil {
+				return Faulted, err
+			}
+			paramPositions[j-1] = pos
+		case 1:
+			// immediate mode
+			if c.writesParam(opCode, j-1) {
+				return Faulted, c.newFault(ErrImmediateWrite, "parameter %d", j)
+			}
+			paramPositions[j-1] = c.ptr + j
+		case 2:
+			// relative mode
+			pos, err := c.getAddress(c.ptr + j)
+			if err != nil {
+				return Faulted, err
+			}
+			paramPositions[j-1] = pos + c.relativeBase
+		default:
+			return Faulted, c.newFault(ErrInvalidParameterMode, "mode %d for parameter %d", modes[j-1], j)
+		}
+
+		if paramPositions[j-1] < 0 {
+			return Faulted, c.newFault(ErrNegativeAddress, "parameter %d resolves to %d", j, paramPositions[j-1])
+		}
+		if paramPositions[j-1] > MaxAddress {
+			return Faulted, c.newFault(ErrAddress, "parameter %d resolves to %d", j, paramPositions[j-1])
+		}
+
+		tmp /= 10
+	}
+
+	var executed *Executed
+	if c.hooks != nil {
+		executed = c.beginExecuted(opCode, paramPositions)
+	}
+
+	instructionPtr := c.instructionPtr
+	c.instructionPtr = c.ptr
+	c.instructions++
+	status := Running
+	jumped := false
+
+	// an instruction that faults isn't executed, so it isn't counted either
+	fault := func(err error) (Status, error) {
+		c.instructionPtr = instructionPtr
+		c.instructions--
+		return Faulted, err
+	}
+
+	switch opCode {
+	case 1, 2:
+		arith := c.add
+		if opCode == 2 {
+			arith = c.mul
+		}
+		if err := arith(paramPositions[2], paramPositions[0], paramPositions[1]); err != nil {
+			return fault(err)
+		}
+	case 3:
+		if err := c.setText(paramPositions[0], c.input[0]); err != nil {
+			return fault(err)
+		}
+		c.input = c.input[1:]
+	case 4:
+		c.lastOut = c.getText(paramPositions[0])
+		c.output = c.getValue(paramPositions[0])
+		status = ProducedOutput
+	case 5, 6:
+		if c.isZero(paramPositions[0]) == (opCode == 6) {
+			pos, err := c.getAddress(paramPositions[1])
+			if err != nil {
+				return fault(err)
+			}
+			c.ptr = pos
+			jumped = true
+		}
+	case 7:
+		if c.cmp(paramPositions[0], paramPositions[1]) < 0 {
+			c.setValue(paramPositions[2], 1)
+		} else {
+			c.setValue(paramPositions[2], 0)
+		}
+	case 8:
+		if c.cmp(paramPositions[0], paramPositions[1]) == 0 {
+			c.setValue(paramPositions[2], 1)
+		} else {
+			c.setValue(paramPositions[2], 0)
+		}
+	case 9:
+		val, err := c.getAddress(paramPositions[0])
+		if err != nil {
+			return fault(err)
+		}
+		c.relativeBase += val
+	default:
+		var x *Invocation
+		status, jumped, x, err = c.executeExtension(opCode, paramPositions)
+		if status == Faulted || status == NeedsInput {
+			c.ptr = x.ptr
+			c.instructionPtr = instructionPtr
+			c.instructions--
+			return status, err
+		}
+		if executed != nil {
+			executed.input, executed.readInput = x.input, x.readInput
+		}
+	}
+
+	if !jumped {
+		c.ptr += length
+	}
+
+	if executed != nil {
+		c.endExecuted(executed, status, jumped)
+	}
+
+	return status, nil
+}
+
+// Step executes a single instruction.  It returns NeedsInput without executing anything when the
+// program wants input and none has been provided.
+func (c *IntCodeComputer) Step() (Status, error) {
+	// outputs are collected by the caller when stepping, not by the output device
+	c.undelivered = false
+	status, err := c.step()
+	if err != nil {
+		c.err = err
+	}
+	return status, err
+}
+
+func (c *IntCodeComputer) run(stopOnOutput bool) (Status, error) {
+	for {
+		if c.budget > 0 && c.instructions >= c.budget {
+			c.err = ErrBudgetExhausted
+			return Faulted, c.err
+		}
+		status, err := c.Step()
+		switch status {
+		case Running:
+			continue
+		case ProducedOutput:
+			if stopOnOutput {
+				return status, nil
+			}
+			c.outputs = append(c.outputs, c.output)
+			continue
+		}
+		return status, err
+	}
+}
+
+// RunUntilOutput executes instructions until the program produces an output, needs input, yields, halts
+// or faults.
+func (c *IntCodeComputer) RunUntilOutput() (Status, error) {
+	return c.run(true)
+}
+
+// RunUntilInputNeeded executes instructions until the program needs input, yields, halts or faults.  Outputs
+// produced along the way are queued and can be collected with TakeOutputs.
+func (c *IntCodeComputer) RunUntilInputNeeded() (Status, error) {
+	return c.run(false)
+}
+
+// ProvideInput queues values for the program's input instructions.
+func (c *IntCodeComputer) ProvideInput(values ...int64) {
+	for _, v := range values {
+		c.input = append(c.input, strconv.FormatInt(v, 10))
+	}
+}
+
+// ProvideInputText queues a value in the string program format, which may need arbitrary precision.
+func (c *IntCodeComputer) ProvideInputText(value string) {
+	c.input = append(c.input, value)
+}
+
+// Output returns the value of the last output instruction.  Use OutputText for values that need
+// arbitrary precision.
+func (c *IntCodeComputer) Output() int64 {
+	return c.output
+}
+
+func (c *IntCodeComputer) OutputText() string {
+	return c.lastOut
+}
+
+// TakeOutputs returns the outputs queued by RunUntilInputNeeded and clears the queue.
+func (c *IntCodeComputer) TakeOutputs() []int64 {
+	outputs := c.outputs
+	c.outputs = nil
+	return outputs
+}
@@ -0,0 +1,52 @@
+package intcode
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_StepAPI(t *testing.T) {
+	c := NewIntCodeComputer(strings.Split(`3,0,4,0,99`, ","), nil, nil, nil, false, nil)
+
+	status, err := c.Step()
+	assert.Nil(t, err)
+	assert.Equal(t, NeedsInput, status)
+
+	c.ProvideInput(42)
+	status, _ = c.Step()
+	assert.Equal(t, Running, status)
+	status, _ = c.Step()
+	assert.Equal(t, ProducedOutput, status)
+	assert.Equal(t, int64(42), c.Output())
+	assert.Equal(t, "42", c.OutputText())
+	status, _ = c.Step()
+	assert.Equal(t, Halted, status)
+	status, _ = c.Step()
+	assert.Equal(t, Halted, status)
+}
+
+func Test_RunUntil(t *testing.T) {
+	// echoes every input doubled, forever
+	c := NewIntCodeComputer(strings.Split(`3,11,1002,11,2,11,4,11,1105,1,0`, ","), nil, nil, nil, false, nil)
+
+	status, err := c.RunUntilOutput()
+	assert.Nil(t, err)
+	assert.Equal(t, NeedsInput, status)
+
+	c.ProvideInput(1, 2, 3)
+	status, _ = c.RunUntilOutput()
+	assert.Equal(t, ProducedOutput, status)
+	assert.Equal(t, int64(2), c.Output())
+
+	status, _ = c.RunUntilInputNeeded()
+	assert.Equal(t, NeedsInput, status)
+	assert.Equal(t, []int64{4, 6}, c.TakeOutputs())
+	assert.Nil(t, c.TakeOutputs())
+
+	c.SetInstructionBudget(c.Instructions() + 2)
+	c.ProvideInput(4)
+	status, err = c.RunUntilInputNeeded()
+	assert.Equal(t, Faulted, status)
+	assert.Equal(t, ErrBudgetExhausted, err)
+}
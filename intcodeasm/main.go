@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mbordner/advent_of_code_2019/intcode"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// usage: intcodeasm <source file> [symbol file]
+//
+// Prints the assembled program as a comma list, and writes the labels to the symbol file, one
+// "name address" pair per line.
+func main() {
+	if len(os.Args) < 2 || len(os.Args) > 3 {
+		fmt.Println("usage: intcodeasm <source file> [symbol file]")
+		os.Exit(1)
+	}
+
+	source, err := ioutil.ReadFile(os.Args[1])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	assembled, err := intcode.Assemble(string(source))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Println(strings.Join(assembled.Program, ","))
+
+	if len(os.Args) == 3 {
+		names := make([]string, 0, len(assembled.Symbols))
+		for name := range assembled.Symbols {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var sb strings.Builder
+		for _, name := range names {
+			sb.WriteString(fmt.Sprintf("%s %d\n", name, assembled.Symbols[name]))
+		}
+		if err := ioutil.WriteFile(os.Args[2], []byte(sb.String()), 0644); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+}
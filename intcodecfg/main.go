@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/mbordner/advent_of_code_2019/intcode"
+	"os"
+)
+
+// usage: intcodecfg [-dot cfg.dot] <program file>
+//
+// Prints a summary of a program's control flow graph: its functions, calls, jumps that can't be
+// followed and self modifying instructions.  With -dot the graph is also written in the Graphviz DOT
+// language, for example to render with dot -Tsvg cfg.dot > cfg.svg.  Files ending in .asm are
+// assembled first, and their labels name the functions.
+func main() {
+	dot := flag.String("dot", "", "file to write the graph to in the DOT language, or - for stdout")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	program, symbols, err := intcode.ReadProgramFile(flag.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	g, err := intcode.BuildCFGWithSymbols(program, symbols)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if *dot == "-" {
+		if err := g.WriteDOT(os.Stdout); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *dot != "" {
+		if err := writeDOT(g, *dot); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	printSummary(g)
+}
+
+func writeDOT(g *intcode.CFG, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	if err := g.WriteDOT(w); err != nil {
+		file.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func printSummary(g *intcode.CFG) {
+	blocks := make(map[int]int)
+	for _, b := range g.Blocks {
+		blocks[b.Function]++
+	}
+	callers := make(map[int]int)
+	for _, call := range g.Calls {
+		if call.Target >= 0 {
+			callers[call.Target]++
+		}
+	}
+
+	fmt.Printf("%d blocks, %d functions, %d calls\n", len(g.Blocks), len(g.Functions), len(g.Calls))
+
+	fmt.Println("\nfunctions:")
+	for _, f := range g.Functions {
+		fmt.Printf("  %6d %-16s %4d blocks %4d callers\n", f, g.Label(f), blocks[f], callers[f])
+	}
+
+	calls := make(map[int]bool)
+	for _, call := range g.Calls {
+		calls[call.Addr] = true
+	}
+	var indirect []*intcode.Instruction
+	for _, b := range g.Blocks {
+		if b.Indirect {
+			indirect = append(indirect, b.Instructions[len(b.Instructions)-1])
+		}
+	}
+	if len(indirect) > 0 {
+		fmt.Println("\njumps that can't be followed:")
+		for _, inst := range indirect {
+			kind := ""
+			if calls[inst.Addr] {
+				kind = " ; call through a function pointer"
+			}
+			fmt.Printf("  %6d: %s%s\n", inst.Addr, inst, kind)
+		}
+	}
+
+	if len(g.SelfModifying) > 0 {
+		fmt.Println("\nself modifying instructions:")
+		for _, m := range g.SelfModifying {
+			fmt.Printf("  %6d writes %d, in the instruction at %d\n", m.Addr, m.Target, m.Instruction)
+		}
+	}
+}
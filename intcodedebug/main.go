@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/mbordner/advent_of_code_2019/intcode"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// historyWindow is how many instructions can be stepped back.
+const historyWindow = 1000000
+
+const help = `commands:
+  b <addr>        break at an address
+  bo <opcode>     break on an opcode
+  w <addr>        watch writes to a memory cell
+  d <addr>        delete the breakpoint at an address
+  do <opcode>     delete the breakpoint on an opcode
+  dw <addr>       delete the watch on a memory cell
+  l               list breakpoints and watches
+  s [n]           single step n instructions
+  c               continue
+  back [n]        step back n instructions
+  bw <addr>       run back to the last write to a memory cell
+  bi              rewind to the last input instruction, dropping queued input
+  r               print registers
+  m <start> [end] dump memory
+  set <addr> <v>  set a memory cell, clearing the history
+  i <v>...        queue input values
+  a <text>        queue text as ASCII input, followed by a newline
+  ascii           toggle printing outputs as ASCII
+  q               quit`
+
+// usage: intcodedebug <program file>
+//
+// Files ending in .asm are assembled first, and their labels can be used in place of addresses.
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Println("usage: intcodedebug <program file>")
+		os.Exit(1)
+	}
+
+	program, symbols, err := intcode.ReadProgramFile(os.Args[1])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	c := intcode.NewIntCodeComputer(program, nil, nil, nil, false, nil)
+
+	d := intcode.NewDebugger(c)
+	d.SetSymbols(symbols)
+	history := intcode.NewRecorder(c, historyWindow)
+	ascii := false
+
+	printOutput := func() {
+		if ascii && c.Output() >= 0 && c.Output() < 128 {
+			fmt.Print(string(rune(c.Output())))
+		} else {
+			fmt.Println("output:", c.OutputText())
+		}
+	}
+
+	report := func(status intcode.Status, err error) {
+		switch status {
+		case intcode.Paused:
+			fmt.Printf("paused at %s: %s\n", d.Describe(c.Registers().Ptr), d.Reason())
+		case intcode.NeedsInput:
+			fmt.Printf("waiting for input at %d\n", c.Registers().Ptr)
+		case intcode.Halted:
+			fmt.Println("halted")
+		case intcode.Faulted:
+			fmt.Println("fault:", err)
+		}
+	}
+
+	fmt.Println(help)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("(icd) ")
+		if !scanner.Scan() {
+			return
+		}
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		args, err := parseArgs(d, fields[1:])
+		if err != nil && fields[0] != "a" {
+			fmt.Println(err)
+			continue
+		}
+
+		switch fields[0] {
+		case "b":
+			for _, a := range args {
+				d.BreakAt(int(a))
+			}
+		case "bo":
+			for _, a := range args {
+				d.BreakOnOpCode(int(a))
+			}
+		case "w":
+			for _, a := range args {
+				d.Watch(int(a))
+			}
+		case "d":
+			for _, a := range args {
+				d.ClearBreakAt(int(a))
+			}
+		case "do":
+			for _, a := range args {
+				d.ClearBreakOnOpCode(int(a))
+			}
+		case "dw":
+			for _, a := range args {
+				d.ClearWatch(int(a))
+			}
+		case "l":
+			fmt.Println(d.Breakpoints())
+		case "s":
+			n := 1
+			if len(args) > 0 {
+				n = int(args[0])
+			}
+			for i := 0; i < n; i++ {
+				status, err := d.Step()
+				if status == intcode.ProducedOutput {
+					printOutput()
+					continue
+				}
+				if status != intcode.Running {
+					report(status, err)
+					break
+				}
+			}
+			printRegisters(d)
+		case "c":
+			for {
+				status, err := d.Continue()
+				if status == intcode.ProducedOutput {
+					printOutput()
+					continue
+				}
+				report(status, err)
+				break
+			}
+		case "back":
+			n := 1
+			if len(args) > 0 {
+				n = int(args[0])
+			}
+			for i := 0; i < n; i++ {
+				if !history.StepBack() {
+					fmt.Println("no more history")
+					break
+				}
+			}
+			printRegisters(d)
+		case "bw":
+			if len(args) != 1 {
+				fmt.Println("bw <addr>")
+				continue
+			}
+			if !history.RunBackToWrite(int(args[0])) {
+				fmt.Println("no recorded write to", d.Describe(int(args[0])))
+			}
+			printRegisters(d)
+		case "bi":
+			input, ok := history.RewindToInput()
+			if !ok {
+				fmt.Println("no recorded input")
+			} else {
+				fmt.Println("dropped input:", strings.Join(input, ","))
+			}
+			printRegisters(d)
+		case "set":
+			if len(args) != 2 {
+				fmt.Println("set <addr> <value>")
+				continue
+			}
+			c.Poke(int(args[0]), args[1])
+			history.Clear()
+		case "r":
+			printRegisters(d)
+		case "m":
+			if len(args) == 0 {
+				fmt.Println("m <start> [end]")
+				continue
+			}
+			end := args[0] + 1
+			if len(args) > 1 {
+				end = args[1]
+			}
+			fmt.Println(d.Dump(int(args[0]), int(end)))
+		case "i":
+			c.ProvideInput(args...)
+		case "a":
+			text := strings.TrimSpace(strings.TrimPrefix(line, "a"))
+			for _, r := range text + "\n" {
+				c.ProvideInput(int64(r))
+			}
+		case "ascii":
+			ascii = !ascii
+			fmt.Println("ascii output:", ascii)
+		case "q":
+			return
+		default:
+			fmt.Println(help)
+		}
+	}
+}
+
+func printRegisters(d *intcode.Debugger) {
+	c := d.Computer()
+	r := c.Registers()
+	fmt.Printf("ptr=%s relativeBase=%d lastOut=%s instructions=%d\n", d.Describe(r.Ptr), r.RelativeBase, r.LastOut, r.Instructions)
+	if inst, ok := c.DisassembleAt(r.Ptr); ok {
+		fmt.Printf("next: %s\n", inst)
+	} else {
+		fmt.Printf("next: %s\n", c.PeekText(r.Ptr))
+	}
+}
+
+// parseArgs parses numbers and labels.
+func parseArgs(d *intcode.Debugger, fields []string) ([]int64, error) {
+	args := make([]int64, len(fields))
+	for i, f := range fields {
+		if addr, ok := d.Symbol(f); ok {
+			args[i] = int64(addr)
+			continue
+		}
+		v, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", f)
+		}
+		args[i] = v
+	}
+	return args, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mbordner/advent_of_code_2019/intcode"
+	"os"
+)
+
+// usage: intcodedisasm <program file>
+//
+// Files ending in .asm are assembled first, and the listing shows their labels.
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Println("usage: intcodedisasm <program file>")
+		os.Exit(1)
+	}
+
+	program, symbols, err := intcode.ReadProgramFile(os.Args[1])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	listing, err := intcode.DisassembleWithSymbols(program, symbols)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Print(listing)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mbordner/advent_of_code_2019/intcode"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+// usage: intcodefuzz [-n 10000] [-size 30] [-steps 2000] [-seed 1]
+//
+// Runs random programs with the interpreter and with the compiled engine, and reports the first
+// instruction where they disagree.
+func main() {
+	n := flag.Int("n", 10000, "number of programs to run")
+	size := flag.Int("size", 30, "maximum number of instructions in a program")
+	steps := flag.Uint64("steps", 2000, "maximum number of instructions to run each program for")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed")
+	flag.Parse()
+
+	r := rand.New(rand.NewSource(*seed))
+	for i := 0; i < *n; i++ {
+		program := intcode.RandomProgram(r, 1+r.Intn(*size))
+		inputs := []int64{r.Int63n(100), r.Int63n(100) - 50, r.Int63n(1 << 40)}
+
+		a := intcode.NewIntCodeComputer(program, nil, nil, nil, false, nil)
+		b := intcode.NewIntCodeComputer(program, nil, nil, nil, false, nil)
+		b.SetCompiled(true)
+		a.ProvideInput(inputs...)
+		b.ProvideInput(inputs...)
+
+		if d := intcode.Differential(a, b, *steps); d != nil {
+			fmt.Printf("seed %d, program %d: %v\n", *seed, i, d)
+			fmt.Printf("inputs: %v\n", inputs)
+			fmt.Printf("program: %s\n", strings.Join(program, ","))
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("seed %d: %d programs agree\n", *seed, *n)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/mbordner/advent_of_code_2019/intcode"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// usage: intcodeprof [-in 1,2,3] [-top 20] [-trace trace.jsonl] <program file>
+//
+// Runs a program with the given inputs until it halts or needs more input, then prints its outputs and
+// a profile of where it spent its instructions.
+func main() {
+	in := flag.String("in", "", "comma separated input values")
+	n := flag.Int("top", 20, "number of addresses and cells to list")
+	trace := flag.String("trace", "", "file to write a JSON lines instruction trace to")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	program, _, err := intcode.ReadProgramFile(flag.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	c := intcode.NewIntCodeComputer(program, nil, nil, nil, false, nil)
+	p := intcode.NewProfiler(c)
+
+	if *trace != "" {
+		file, err := os.Create(*trace)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		w := bufio.NewWriter(file)
+		defer w.Flush()
+		t := intcode.NewTracer(c, w)
+		defer func() {
+			if t.Err() != nil {
+				fmt.Println("trace:", t.Err())
+			}
+		}()
+	}
+
+	if *in != "" {
+		for _, v := range strings.Split(*in, ",") {
+			c.ProvideInputText(strings.TrimSpace(v))
+		}
+	}
+
+	status, err := c.RunUntilInputNeeded()
+	outputs := c.TakeOutputs()
+	values := make([]string, len(outputs))
+	for i, o := range outputs {
+		values[i] = strconv.FormatInt(o, 10)
+	}
+	fmt.Println("outputs:", strings.Join(values, ","))
+	fmt.Println("status:", status)
+	if err != nil {
+		fmt.Println("error:", err)
+	}
+	fmt.Println()
+
+	p.Report(os.Stdout, *n)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mbordner/advent_of_code_2019/intcode"
+	"os"
+	"time"
+)
+
+// usage: intcodeserve [-addr localhost:2019] [-idle 10m] [-snapshots dir] [-budget n] <program file>
+//
+// Serves an ASCII program, such as day25's adventure or day21's springdroid, on a TCP port.  Each
+// connection gets its own computer, and lines sent on it are the program's input, so it can be played
+// with nc localhost 2019.  Lines starting with ! are server commands; !help lists them.
+func main() {
+	addr := flag.String("addr", "localhost:2019", "address to listen on")
+	idle := flag.Duration("idle", 10*time.Minute, "end sessions idle for this long, or 0 for never")
+	snapshots := flag.String("snapshots", "", "directory for the !snapshot command to save sessions in")
+	budget := flag.Uint64("budget", 100000000, "most instructions a session runs for each line, or 0 for no limit")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	program, _, err := intcode.ReadProgramFile(flag.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	s := intcode.NewServer(program)
+	s.IdleTimeout = *idle
+	s.SnapshotDir = *snapshots
+	s.Budget = *budget
+
+	fmt.Println("listening on", *addr)
+	if err := s.ListenAndServe(*addr); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}